@@ -19,26 +19,41 @@ package main
 import (
 	"flag"
 	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis"
-	autoscalingv1beta1 "github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
 	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/controller"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	klog "k8s.io/klog/v2"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+	"time"
 )
 
 var (
 	enableLeaderElection bool
 	pprofAddr            string
 	metricsAddr          string
+	defaultJitterSeconds int
+	jobWorkers           int
+	scaleQPS             float64
+	scaleBurst           int
+	statusBatchWindow    time.Duration
 )
 
 func main() {
 	flag.StringVar(&pprofAddr, "pprof-bind-address", ":6060", "The address the pprof endpoint binds to.")
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
+	flag.IntVar(&defaultJitterSeconds, "default-jitter-seconds", 0, "The jitterSeconds of the jobs which don't set it, 0 disables the jitter.")
+	flag.IntVar(&jobWorkers, "job-workers", 20, "The number of the jobs which run at the same time, 0 means no limit.")
+	flag.Float64Var(&scaleQPS, "scale-qps", 20, "The rate of the calls of the jobs to the API server, 0 means no limit.")
+	flag.IntVar(&scaleBurst, "scale-burst", 40, "The burst of the calls of the jobs to the API server.")
+	flag.DurationVar(&statusBatchWindow, "status-batch-window", time.Second, "The time in which the results of the jobs of a cronHPA are written to its status together, 0 writes them at once.")
 	flag.Parse()
+	if defaultJitterSeconds < 0 {
+		klog.Errorf("Failed to start cronHPA controller,because of negative default-jitter-seconds %d", defaultJitterSeconds)
+		os.Exit(1)
+	}
 	klog.Info("Start cronHPA controller.")
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		LeaderElection:     enableLeaderElection,
@@ -57,14 +72,39 @@ func main() {
 		os.Exit(1)
 	}
 
-	err = ctrl.NewControllerManagedBy(mgr).
-		For(&autoscalingv1beta1.CronHorizontalPodAutoscaler{}).
-		Complete(controller.NewReconciler(mgr))
+	// CRDs are watched to refresh the kinds of the targets.
+	err = apiextensionsv1.AddToScheme(mgr.GetScheme())
+	if err != nil {
+		klog.Errorf("Failed to add apiextensions to scheme,because of %v", err)
+		os.Exit(1)
+	}
+
+	r := controller.NewReconciler(mgr, controller.CronManagerOptions{
+		DefaultJitterSeconds: int32(defaultJitterSeconds),
+		Workers:              jobWorkers,
+		ScaleQPS:             float32(scaleQPS),
+		ScaleBurst:           scaleBurst,
+		StatusBatchWindow:    statusBatchWindow,
+	})
+	err = r.SetupWithManager(mgr)
 	if err != nil {
 		klog.Errorf("Failed to set up controller watch loop,because of %v", err)
 		os.Exit(1)
 	}
 
+	// cluster policies share the cron engine with the cronHPAs.
+	err = controller.NewClusterReconciler(mgr, r.CronManager).SetupWithManager(mgr)
+	if err != nil {
+		klog.Errorf("Failed to set up clusterCronHPA controller watch loop,because of %v", err)
+		os.Exit(1)
+	}
+
+	err = controller.NewScalingCalendarReconciler(mgr).SetupWithManager(mgr)
+	if err != nil {
+		klog.Errorf("Failed to set up scalingCalendar controller watch loop,because of %v", err)
+		os.Exit(1)
+	}
+
 	go func() {
 		http.ListenAndServe(pprofAddr, nil)
 	}()
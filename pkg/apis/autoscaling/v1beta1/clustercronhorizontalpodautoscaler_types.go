@@ -0,0 +1,106 @@
+/*
+Copyright 2018 zhongwei.lzw@alibaba-inc.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterCronHorizontalPodAutoscalerSpec defines the desired state of ClusterCronHorizontalPodAutoscaler
+type ClusterCronHorizontalPodAutoscalerSpec struct {
+	// NamespaceSelector selects the namespaces which the policy applies to.
+	// All namespaces are selected if it is empty.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// ScaleTargetSelector selects the workloads in every selected namespace.
+	ScaleTargetSelector ScaleTargetSelector `json:"scaleTargetSelector"`
+	ExcludeDates        []string            `json:"excludeDates,omitempty"`
+	// +optional
+	IncludeDates []string `json:"includeDates,omitempty"`
+	// +optional
+	ExcludeWindows []ExcludeWindow `json:"excludeWindows,omitempty"`
+	// +optional
+	ExcludeCalendars []string `json:"excludeCalendars,omitempty"`
+	// +optional
+	IncludeCalendars []string `json:"includeCalendars,omitempty"`
+	// +optional
+	ScheduleFormat ScheduleFormat `json:"scheduleFormat,omitempty"`
+	// +optional
+	DSTPolicy DSTPolicy `json:"dstPolicy,omitempty"`
+	// Timezone of the schedules and excludeDates. Defaults to the timezone
+	// annotation of every namespace and then the timezone of the controller.
+	// +optional
+	Timezone string `json:"timezone,omitempty"`
+	Jobs     []Job  `json:"jobs"`
+}
+
+type NamespaceCondition struct {
+	Namespace string `json:"namespace"`
+
+	// OptedOut is true when a cronHPA in the namespace opts out of the policy.
+	// +optional
+	OptedOut bool `json:"optedOut,omitempty"`
+
+	// NamespaceDefaults which the jobs in the namespace are created with.
+	// +optional
+	NamespaceDefaults *NamespaceDefaults `json:"namespaceDefaults,omitempty"`
+
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// ClusterCronHorizontalPodAutoscalerStatus defines the observed state of ClusterCronHorizontalPodAutoscaler
+type ClusterCronHorizontalPodAutoscalerStatus struct {
+	// +optional
+	ScaleTargetSelector ScaleTargetSelector `json:"scaleTargetSelector,omitempty"`
+	// +optional
+	ExcludeDates []string `json:"excludeDates,omitempty"`
+	// +optional
+	ExcludeWindows []ExcludeWindow `json:"excludeWindows,omitempty"`
+	// +optional
+	ExcludeCalendars []string `json:"excludeCalendars,omitempty"`
+	// +optional
+	IncludeCalendars []string `json:"includeCalendars,omitempty"`
+	// +optional
+	Timezone string `json:"timezone,omitempty"`
+
+	// Results of the jobs in every selected namespace.
+	Namespaces []NamespaceCondition `json:"namespaces,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=clustercronhpa
+// ClusterCronHorizontalPodAutoscaler is the Schema for the clustercronhorizontalpodautoscalers API
+type ClusterCronHorizontalPodAutoscaler struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterCronHorizontalPodAutoscalerSpec   `json:"spec,omitempty"`
+	Status ClusterCronHorizontalPodAutoscalerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// ClusterCronHorizontalPodAutoscalerList contains a list of ClusterCronHorizontalPodAutoscaler
+type ClusterCronHorizontalPodAutoscalerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterCronHorizontalPodAutoscaler `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterCronHorizontalPodAutoscaler{}, &ClusterCronHorizontalPodAutoscalerList{})
+}
@@ -27,23 +27,233 @@ import (
 type CronHorizontalPodAutoscalerSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
-	ExcludeDates   []string       `json:"excludeDates,omitempty"`
-	ScaleTargetRef ScaleTargetRef `json:"scaleTargetRef"`
-	Jobs           []Job          `json:"jobs"`
+	ExcludeDates []string `json:"excludeDates,omitempty"`
+	// IncludeDates make the jobs fire on the dates at the time of day of their schedules,
+	// even if the dates are excluded or the schedules don't match the days.
+	// +optional
+	IncludeDates []string `json:"includeDates,omitempty"`
+	// ExcludeWindows skip the jobs which fire within the windows.
+	// +optional
+	ExcludeWindows []ExcludeWindow `json:"excludeWindows,omitempty"`
+	// ExcludeCalendars are the names of ScalingCalendars whose dates are excluded.
+	// +optional
+	ExcludeCalendars []string `json:"excludeCalendars,omitempty"`
+	// IncludeCalendars are the names of ScalingCalendars whose dates are included like includeDates.
+	// +optional
+	IncludeCalendars []string `json:"includeCalendars,omitempty"`
+	// ExcludeICalendars are iCalendar data in the ConfigMaps of the namespace whose events are excluded.
+	// +optional
+	ExcludeICalendars []ICalendarRef `json:"excludeICalendars,omitempty"`
+	// IncludeICalendars are iCalendar data in the ConfigMaps of the namespace whose events are included.
+	// +optional
+	IncludeICalendars []ICalendarRef `json:"includeICalendars,omitempty"`
+	// ScheduleFormat of the schedules of the jobs, Seconds by default.
+	// +optional
+	ScheduleFormat ScheduleFormat `json:"scheduleFormat,omitempty"`
+	// DSTPolicy defines the executions at the times which don't exist or happen twice
+	// on the daylight saving time transitions of the timezone, runAtNextValidTime by default.
+	// +optional
+	DSTPolicy DSTPolicy `json:"dstPolicy,omitempty"`
+	// Timezone of the schedules and excludeDates, such as "Asia/Shanghai".
+	// Defaults to the timezone annotation of the namespace and then the timezone of the controller.
+	// +optional
+	Timezone string `json:"timezone,omitempty"`
+	// +optional
+	ScaleTargetRef ScaleTargetRef `json:"scaleTargetRef,omitempty"`
+	// ScaleTargetRefs lists more workloads which are scaled by every job.
+	// +optional
+	ScaleTargetRefs []ScaleTargetRef `json:"scaleTargetRefs,omitempty"`
+	// ScaleTargetSelector selects workloads of one kind in the namespace by labels.
+	// The selector is evaluated on every execution, so new workloads are picked up.
+	// +optional
+	ScaleTargetSelector *ScaleTargetSelector `json:"scaleTargetSelector,omitempty"`
+	// Distribution splits the targetSize of every job across the targets.
+	// Without it every target is scaled to the targetSize.
+	// +optional
+	Distribution *DistributionPolicy `json:"distribution,omitempty"`
+	// Stages scale the targets in order. Scale-up runs the stages in the listed
+	// order and scale-down runs them in reverse unless scaleDownOrder is set.
+	// +optional
+	Stages []ScalingStage `json:"stages,omitempty"`
+	// ScaleDownOrder lists the names of the stages in the order of scale-down.
+	// +optional
+	ScaleDownOrder []string `json:"scaleDownOrder,omitempty"`
+	// ClusterPolicyOptOut lists the ClusterCronHorizontalPodAutoscalers which don't apply
+	// to this namespace, "*" opts out of all of them. The workloads scaled by this cronHPA
+	// are always left out of the cluster policies.
+	// +optional
+	ClusterPolicyOptOut []string `json:"clusterPolicyOptOut,omitempty"`
+	// Profile lists the breakpoints of a daily or weekly replica profile, such as "08:00=10"
+	// or "MON-FRI 08:00:30=10". The targets are scaled to the targetSize of a breakpoint at its
+	// time of day on the days of week of it, which is every day if it is omitted.
+	// +optional
+	Profile []string `json:"profile,omitempty"`
+	// Jobs are optional when the profile is set.
+	// +optional
+	Jobs []Job `json:"jobs,omitempty"`
 }
 
 type Job struct {
 	Name     string `json:"name"`
 	Schedule string `json:"schedule"`
+	// ScheduleFormat of the schedule, which overrides the one of the cronHPA.
+	// +optional
+	ScheduleFormat ScheduleFormat `json:"scheduleFormat,omitempty"`
 	// job will only run once if enabled.
 	RunOnce    bool  `json:"runOnce,omitempty"`
 	TargetSize int32 `json:"targetSize"`
+	// ActiveFrom is the time in the timezone of the cronHPA before which the job is not registered,
+	// such as "2026-11-01" or "2026-11-01T08:00".
+	// +optional
+	ActiveFrom string `json:"activeFrom,omitempty"`
+	// ActiveUntil is the time after which the job is removed, which is not included.
+	// +optional
+	ActiveUntil string `json:"activeUntil,omitempty"`
+	// MaxRuns removes the job after it has run the times. The skipped executions are not counted.
+	// +optional
+	MaxRuns int32 `json:"maxRuns,omitempty"`
+	// LeadTime fires the job earlier than the time of the schedule, such as "5m", so the pods
+	// of a scale-up are ready at the business time. The status still shows the business time.
+	// +optional
+	LeadTime string `json:"leadTime,omitempty"`
+	// AdaptiveLeadTime measures how long the targets took to become ready after the previous
+	// scale-up and uses it as the lead time when it is longer than the leadTime.
+	// +optional
+	AdaptiveLeadTime bool `json:"adaptiveLeadTime,omitempty"`
+	// Priority orders the executions waiting for a worker of the controller, the higher first.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+	// JitterSeconds delays the executions by an offset between 0 and the seconds, which is derived
+	// from the job and doesn't change across restarts. It overrides the default of the controller,
+	// and 0 disables the jitter.
+	// +optional
+	JitterSeconds *int32 `json:"jitterSeconds,omitempty"`
+	// ExcludeDates of the job, which are added to the global ones.
+	// +optional
+	ExcludeDates []string `json:"excludeDates,omitempty"`
+	// IncludeDates of the job, which are added to the global ones.
+	// +optional
+	IncludeDates []string `json:"includeDates,omitempty"`
+	// ExcludeCalendars of the job, which are added to the global ones.
+	// +optional
+	ExcludeCalendars []string `json:"excludeCalendars,omitempty"`
+	// IncludeCalendars of the job, which are added to the global ones.
+	// +optional
+	IncludeCalendars []string `json:"includeCalendars,omitempty"`
 }
 
 type ScaleTargetRef struct {
 	ApiVersion string `json:"apiVersion"`
 	Kind       string `json:"kind"`
 	Name       string `json:"name"`
+	// ReplicasPath is the field of the replicas in a workload without the scale subresource,
+	// as a JSONPath such as .spec.replicas or a JSON pointer such as /spec/replicas.
+	// The workload is read and patched directly instead of the scale subresource.
+	// +optional
+	ReplicasPath string `json:"replicasPath,omitempty"`
+}
+
+type ScaleTargetSelector struct {
+	ApiVersion string                `json:"apiVersion"`
+	Kind       string                `json:"kind"`
+	Selector   *metav1.LabelSelector `json:"selector"`
+}
+
+// DistributionPolicy splits the targetSize of a job across the targets by weight.
+// The remainder of the division goes to the targets with the largest fractions.
+type DistributionPolicy struct {
+	// Targets which are not listed get the weight 1.
+	// +optional
+	Targets []DistributionTarget `json:"targets,omitempty"`
+}
+
+type DistributionTarget struct {
+	// Name of the target workload.
+	Name string `json:"name"`
+	// +optional
+	Weight *int32 `json:"weight,omitempty"`
+	// The share of the target never goes below minReplicas.
+	// +optional
+	MinReplicas int32 `json:"minReplicas,omitempty"`
+}
+
+// ScalingStage is a group of targets which are scaled together.
+// Targets which are not in any stage are scaled after all the stages of scale-up
+// and before all the stages of scale-down.
+type ScalingStage struct {
+	Name string `json:"name"`
+	// Names of the target workloads in this stage.
+	Targets []string `json:"targets"`
+	// Wait until the targets of this stage are ready before the next stage starts.
+	// +optional
+	WaitForReady bool `json:"waitForReady,omitempty"`
+	// Defaults to 300 seconds.
+	// +optional
+	ReadyTimeoutSeconds int32 `json:"readyTimeoutSeconds,omitempty"`
+}
+
+// ScheduleFormat is the format of a job schedule.
+// Seconds is the 6-field format beginning with seconds, Standard is the 5-field crontab format
+// and Auto picks one of them by the number of fields.
+// +kubebuilder:validation:Enum=Seconds;Standard;Auto
+type ScheduleFormat string
+
+const (
+	Seconds  ScheduleFormat = "Seconds"
+	Standard ScheduleFormat = "Standard"
+	Auto     ScheduleFormat = "Auto"
+)
+
+// DSTPolicy is the policy of the daylight saving time transitions.
+// runAtNextValidTime runs the nonexistent times at the end of the gap and the repeated times once,
+// runOnceOnRepeat skips the nonexistent times and runs the repeated times once,
+// and skip skips both of them.
+// +kubebuilder:validation:Enum=skip;runAtNextValidTime;runOnceOnRepeat
+type DSTPolicy string
+
+const (
+	DSTSkip               DSTPolicy = "skip"
+	DSTRunAtNextValidTime DSTPolicy = "runAtNextValidTime"
+	DSTRunOnceOnRepeat    DSTPolicy = "runOnceOnRepeat"
+)
+
+// ExcludeWindowRecurrence repeats an exclude window.
+// +kubebuilder:validation:Enum=Daily;Weekly;Monthly;Yearly
+type ExcludeWindowRecurrence string
+
+const (
+	Daily   ExcludeWindowRecurrence = "Daily"
+	Weekly  ExcludeWindowRecurrence = "Weekly"
+	Monthly ExcludeWindowRecurrence = "Monthly"
+	Yearly  ExcludeWindowRecurrence = "Yearly"
+)
+
+// ExcludeWindow is a period of time in which the jobs are skipped.
+type ExcludeWindow struct {
+	Name string `json:"name"`
+	// Start of the window in the timezone of the cronHPA, such as "2026-11-11T00:00".
+	Start string `json:"start"`
+	// End of the window, which is not included.
+	End string `json:"end"`
+	// Recurrence repeats the window from the start. The window happens once if it is empty.
+	// +optional
+	Recurrence ExcludeWindowRecurrence `json:"recurrence,omitempty"`
+	// Jobs lists the names of the jobs which the window applies to, all the jobs if it is empty.
+	// +optional
+	Jobs []string `json:"jobs,omitempty"`
+}
+
+// NamespaceDefaults are read from the annotations of the namespace and merged into
+// every cronHPA in it.
+type NamespaceDefaults struct {
+	// +optional
+	Timezone string `json:"timezone,omitempty"`
+	// Added to the excludeDates of the cronHPA.
+	// +optional
+	ExcludeDates []string `json:"excludeDates,omitempty"`
+	// Disabled skips all the jobs in the namespace.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
 }
 
 type JobState string
@@ -52,6 +262,11 @@ const (
 	Succeed   JobState = "Succeed"
 	Failed    JobState = "Failed"
 	Submitted JobState = "Submitted"
+	Skipped   JobState = "Skipped"
+	// the job is removed after its activeUntil or maxRuns.
+	Expired JobState = "Expired"
+	// the job is registered at its activeFrom.
+	NotYetActive JobState = "NotYetActive"
 )
 
 type Condition struct {
@@ -62,10 +277,43 @@ type Condition struct {
 
 	Schedule string `json:"schedule"`
 
+	// NormalizedSchedule is the 6-field schedule which the job runs with.
+	// +optional
+	NormalizedSchedule string `json:"normalizedSchedule,omitempty"`
+
 	TargetSize int32 `json:"targetSize"`
 
 	RunOnce bool `json:"runOnce"`
 
+	// RunCount is the number of the executions which are not skipped.
+	// +optional
+	RunCount int32 `json:"runCount,omitempty"`
+
+	// DSTAdjustment describes the last time when the dstPolicy was applied to the job.
+	// +optional
+	DSTAdjustment string `json:"dstAdjustment,omitempty"`
+
+	// JitterOffset is the delay of the executions in seconds by the jitterSeconds.
+	// +optional
+	JitterOffset int32 `json:"jitterOffset,omitempty"`
+
+	// NextFireTime is the time of the next execution including the jitter.
+	// +optional
+	NextFireTime *metav1.Time `json:"nextFireTime,omitempty"`
+
+	// NextBusinessTime is the time of the schedule which the next execution is for.
+	// +optional
+	NextBusinessTime *metav1.Time `json:"nextBusinessTime,omitempty"`
+
+	// LeadTime is the lead time which the job fires with.
+	// +optional
+	LeadTime string `json:"leadTime,omitempty"`
+
+	// ReadyDuration is the time which the targets took to become ready after the last scale-up,
+	// which is measured with the adaptiveLeadTime.
+	// +optional
+	ReadyDuration string `json:"readyDuration,omitempty"`
+
 	State JobState `json:"state"`
 
 	LastProbeTime metav1.Time `json:"lastProbeTime"`
@@ -73,17 +321,78 @@ type Condition struct {
 	// Human readable message indicating details about last transition.
 	// +optional
 	Message string `json:"message"`
+
+	// Results of the last execution for every target.
+	// +optional
+	Targets []TargetCondition `json:"targets,omitempty"`
+}
+
+type TargetCondition struct {
+	ApiVersion string `json:"apiVersion"`
+
+	Kind string `json:"kind"`
+
+	Name string `json:"name"`
+
+	// The share of the job targetSize applied to this target.
+	// +optional
+	TargetSize int32 `json:"targetSize,omitempty"`
+
+	// +optional
+	Stage string `json:"stage,omitempty"`
+
+	State JobState `json:"state"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // CronHorizontalPodAutoscalerStatus defines the observed state of CronHorizontalPodAutoscaler
 type CronHorizontalPodAutoscalerStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	ScaleTargetRef ScaleTargetRef `json:"scaleTargetRef,omitempty"`
-	ExcludeDates   []string       `json:"excludeDates,omitempty"`
+	ScaleTargetRef      ScaleTargetRef       `json:"scaleTargetRef,omitempty"`
+	ScaleTargetRefs     []ScaleTargetRef     `json:"scaleTargetRefs,omitempty"`
+	ScaleTargetSelector *ScaleTargetSelector `json:"scaleTargetSelector,omitempty"`
+	Distribution        *DistributionPolicy  `json:"distribution,omitempty"`
+	Stages              []ScalingStage       `json:"stages,omitempty"`
+	ScaleDownOrder      []string             `json:"scaleDownOrder,omitempty"`
+	ExcludeDates        []string             `json:"excludeDates,omitempty"`
+	ExcludeWindows      []ExcludeWindow      `json:"excludeWindows,omitempty"`
+	ExcludeCalendars    []string             `json:"excludeCalendars,omitempty"`
+	IncludeCalendars    []string             `json:"includeCalendars,omitempty"`
+	Timezone            string               `json:"timezone,omitempty"`
+	// CalendarConditions are the results of parsing the iCalendar sources.
+	CalendarConditions []CalendarCondition `json:"calendarConditions,omitempty"`
+	// NamespaceDefaults which the jobs are created with.
+	NamespaceDefaults *NamespaceDefaults `json:"namespaceDefaults,omitempty"`
+	// ProfileStatus is the status of the profile instead of a condition for every breakpoint.
+	ProfileStatus *ProfileStatus `json:"profileStatus,omitempty"`
 	// Important: Run "make" to regenerate code after modifying this file
 	Conditions []Condition `json:"conditions,omitempty"`
 }
 
+// ProfileStatus shows the breakpoints of the profile around now and the last execution.
+type ProfileStatus struct {
+	// Current is the last breakpoint before now.
+	// +optional
+	Current string `json:"current,omitempty"`
+	// Next is the first breakpoint after now.
+	// +optional
+	Next string `json:"next,omitempty"`
+	// +optional
+	NextTime *metav1.Time `json:"nextTime,omitempty"`
+	// State and Message of the last execution.
+	// +optional
+	State JobState `json:"state,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+	// +optional
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
+	// Results of the last execution for every target.
+	// +optional
+	Targets []TargetCondition `json:"targets,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:shortName=cronhpa
 // CronHorizontalPodAutoscaler is the Schema for the cronhorizontalpodautoscalers API
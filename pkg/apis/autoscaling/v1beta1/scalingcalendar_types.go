@@ -0,0 +1,93 @@
+/*
+Copyright 2018 zhongwei.lzw@alibaba-inc.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ScalingCalendarSpec defines the dates of ScalingCalendar
+type ScalingCalendarSpec struct {
+	Dates []CalendarDate `json:"dates,omitempty"`
+	// ICalendars import the events of iCalendar data in ConfigMaps as dates.
+	// +optional
+	ICalendars []ICalendarRef `json:"iCalendars,omitempty"`
+}
+
+type CalendarDate struct {
+	// Date is an ISO date like 2026-10-01, an inclusive range like 2026-10-01..2026-10-07
+	// or a cron expression which matches the day, the same as excludeDates.
+	Date string `json:"date"`
+	// Label of the date, such as the name of the holiday.
+	// +optional
+	Label string `json:"label,omitempty"`
+}
+
+// ICalendarRef refers to iCalendar(.ics) data in a key of a ConfigMap.
+type ICalendarRef struct {
+	// Namespace of the ConfigMap, which is required by ScalingCalendar.
+	// A cronHPA always reads the ConfigMap in its own namespace.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+}
+
+// CalendarCondition is the result of parsing an iCalendar source.
+type CalendarCondition struct {
+	// Source is the ConfigMap key in the form of namespace/name/key.
+	Source string `json:"source"`
+	// ResourceVersion of the ConfigMap which is parsed.
+	// +optional
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+	// Parsed is false if the data could not be read or some events could not be parsed.
+	Parsed bool `json:"parsed"`
+	// Dates is the number of dates imported from the events.
+	// +optional
+	Dates int32 `json:"dates,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ScalingCalendarStatus defines the observed state of ScalingCalendar
+type ScalingCalendarStatus struct {
+	// +optional
+	Conditions []CalendarCondition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=scalingcal
+// ScalingCalendar is the Schema for the scalingcalendars API
+type ScalingCalendar struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ScalingCalendarSpec   `json:"spec,omitempty"`
+	Status ScalingCalendarStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// ScalingCalendarList contains a list of ScalingCalendar
+type ScalingCalendarList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ScalingCalendar `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ScalingCalendar{}, &ScalingCalendarList{})
+}
@@ -21,13 +21,216 @@ limitations under the License.
 package v1beta1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CalendarCondition) DeepCopyInto(out *CalendarCondition) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CalendarCondition.
+func (in *CalendarCondition) DeepCopy() *CalendarCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(CalendarCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CalendarDate) DeepCopyInto(out *CalendarDate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CalendarDate.
+func (in *CalendarDate) DeepCopy() *CalendarDate {
+	if in == nil {
+		return nil
+	}
+	out := new(CalendarDate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterCronHorizontalPodAutoscaler) DeepCopyInto(out *ClusterCronHorizontalPodAutoscaler) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterCronHorizontalPodAutoscaler.
+func (in *ClusterCronHorizontalPodAutoscaler) DeepCopy() *ClusterCronHorizontalPodAutoscaler {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterCronHorizontalPodAutoscaler)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterCronHorizontalPodAutoscaler) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterCronHorizontalPodAutoscalerList) DeepCopyInto(out *ClusterCronHorizontalPodAutoscalerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterCronHorizontalPodAutoscaler, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterCronHorizontalPodAutoscalerList.
+func (in *ClusterCronHorizontalPodAutoscalerList) DeepCopy() *ClusterCronHorizontalPodAutoscalerList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterCronHorizontalPodAutoscalerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterCronHorizontalPodAutoscalerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterCronHorizontalPodAutoscalerSpec) DeepCopyInto(out *ClusterCronHorizontalPodAutoscalerSpec) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	in.ScaleTargetSelector.DeepCopyInto(&out.ScaleTargetSelector)
+	if in.ExcludeDates != nil {
+		in, out := &in.ExcludeDates, &out.ExcludeDates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeDates != nil {
+		in, out := &in.IncludeDates, &out.IncludeDates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeWindows != nil {
+		in, out := &in.ExcludeWindows, &out.ExcludeWindows
+		*out = make([]ExcludeWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExcludeCalendars != nil {
+		in, out := &in.ExcludeCalendars, &out.ExcludeCalendars
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeCalendars != nil {
+		in, out := &in.IncludeCalendars, &out.IncludeCalendars
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Jobs != nil {
+		in, out := &in.Jobs, &out.Jobs
+		*out = make([]Job, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterCronHorizontalPodAutoscalerSpec.
+func (in *ClusterCronHorizontalPodAutoscalerSpec) DeepCopy() *ClusterCronHorizontalPodAutoscalerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterCronHorizontalPodAutoscalerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterCronHorizontalPodAutoscalerStatus) DeepCopyInto(out *ClusterCronHorizontalPodAutoscalerStatus) {
+	*out = *in
+	in.ScaleTargetSelector.DeepCopyInto(&out.ScaleTargetSelector)
+	if in.ExcludeDates != nil {
+		in, out := &in.ExcludeDates, &out.ExcludeDates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeWindows != nil {
+		in, out := &in.ExcludeWindows, &out.ExcludeWindows
+		*out = make([]ExcludeWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExcludeCalendars != nil {
+		in, out := &in.ExcludeCalendars, &out.ExcludeCalendars
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeCalendars != nil {
+		in, out := &in.IncludeCalendars, &out.IncludeCalendars
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]NamespaceCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterCronHorizontalPodAutoscalerStatus.
+func (in *ClusterCronHorizontalPodAutoscalerStatus) DeepCopy() *ClusterCronHorizontalPodAutoscalerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterCronHorizontalPodAutoscalerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Condition) DeepCopyInto(out *Condition) {
 	*out = *in
+	if in.NextFireTime != nil {
+		in, out := &in.NextFireTime, &out.NextFireTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextBusinessTime != nil {
+		in, out := &in.NextBusinessTime, &out.NextBusinessTime
+		*out = (*in).DeepCopy()
+	}
 	in.LastProbeTime.DeepCopyInto(&out.LastProbeTime)
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]TargetCondition, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
@@ -107,11 +310,82 @@ func (in *CronHorizontalPodAutoscalerSpec) DeepCopyInto(out *CronHorizontalPodAu
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.IncludeDates != nil {
+		in, out := &in.IncludeDates, &out.IncludeDates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeWindows != nil {
+		in, out := &in.ExcludeWindows, &out.ExcludeWindows
+		*out = make([]ExcludeWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExcludeCalendars != nil {
+		in, out := &in.ExcludeCalendars, &out.ExcludeCalendars
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeCalendars != nil {
+		in, out := &in.IncludeCalendars, &out.IncludeCalendars
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeICalendars != nil {
+		in, out := &in.ExcludeICalendars, &out.ExcludeICalendars
+		*out = make([]ICalendarRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeICalendars != nil {
+		in, out := &in.IncludeICalendars, &out.IncludeICalendars
+		*out = make([]ICalendarRef, len(*in))
+		copy(*out, *in)
+	}
 	out.ScaleTargetRef = in.ScaleTargetRef
+	if in.ScaleTargetRefs != nil {
+		in, out := &in.ScaleTargetRefs, &out.ScaleTargetRefs
+		*out = make([]ScaleTargetRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.ScaleTargetSelector != nil {
+		in, out := &in.ScaleTargetSelector, &out.ScaleTargetSelector
+		*out = new(ScaleTargetSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Distribution != nil {
+		in, out := &in.Distribution, &out.Distribution
+		*out = new(DistributionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Stages != nil {
+		in, out := &in.Stages, &out.Stages
+		*out = make([]ScalingStage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ScaleDownOrder != nil {
+		in, out := &in.ScaleDownOrder, &out.ScaleDownOrder
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClusterPolicyOptOut != nil {
+		in, out := &in.ClusterPolicyOptOut, &out.ClusterPolicyOptOut
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Profile != nil {
+		in, out := &in.Profile, &out.Profile
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Jobs != nil {
 		in, out := &in.Jobs, &out.Jobs
 		*out = make([]Job, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
@@ -129,11 +403,70 @@ func (in *CronHorizontalPodAutoscalerSpec) DeepCopy() *CronHorizontalPodAutoscal
 func (in *CronHorizontalPodAutoscalerStatus) DeepCopyInto(out *CronHorizontalPodAutoscalerStatus) {
 	*out = *in
 	out.ScaleTargetRef = in.ScaleTargetRef
+	if in.ScaleTargetRefs != nil {
+		in, out := &in.ScaleTargetRefs, &out.ScaleTargetRefs
+		*out = make([]ScaleTargetRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.ScaleTargetSelector != nil {
+		in, out := &in.ScaleTargetSelector, &out.ScaleTargetSelector
+		*out = new(ScaleTargetSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Distribution != nil {
+		in, out := &in.Distribution, &out.Distribution
+		*out = new(DistributionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Stages != nil {
+		in, out := &in.Stages, &out.Stages
+		*out = make([]ScalingStage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ScaleDownOrder != nil {
+		in, out := &in.ScaleDownOrder, &out.ScaleDownOrder
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.ExcludeDates != nil {
 		in, out := &in.ExcludeDates, &out.ExcludeDates
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ExcludeWindows != nil {
+		in, out := &in.ExcludeWindows, &out.ExcludeWindows
+		*out = make([]ExcludeWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExcludeCalendars != nil {
+		in, out := &in.ExcludeCalendars, &out.ExcludeCalendars
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeCalendars != nil {
+		in, out := &in.IncludeCalendars, &out.IncludeCalendars
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CalendarConditions != nil {
+		in, out := &in.CalendarConditions, &out.CalendarConditions
+		*out = make([]CalendarCondition, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceDefaults != nil {
+		in, out := &in.NamespaceDefaults, &out.NamespaceDefaults
+		*out = new(NamespaceDefaults)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProfileStatus != nil {
+		in, out := &in.ProfileStatus, &out.ProfileStatus
+		*out = new(ProfileStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]Condition, len(*in))
@@ -153,9 +486,111 @@ func (in *CronHorizontalPodAutoscalerStatus) DeepCopy() *CronHorizontalPodAutosc
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DistributionPolicy) DeepCopyInto(out *DistributionPolicy) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]DistributionTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DistributionPolicy.
+func (in *DistributionPolicy) DeepCopy() *DistributionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DistributionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DistributionTarget) DeepCopyInto(out *DistributionTarget) {
+	*out = *in
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DistributionTarget.
+func (in *DistributionTarget) DeepCopy() *DistributionTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(DistributionTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExcludeWindow) DeepCopyInto(out *ExcludeWindow) {
+	*out = *in
+	if in.Jobs != nil {
+		in, out := &in.Jobs, &out.Jobs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExcludeWindow.
+func (in *ExcludeWindow) DeepCopy() *ExcludeWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(ExcludeWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ICalendarRef) DeepCopyInto(out *ICalendarRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ICalendarRef.
+func (in *ICalendarRef) DeepCopy() *ICalendarRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ICalendarRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Job) DeepCopyInto(out *Job) {
 	*out = *in
+	if in.JitterSeconds != nil {
+		in, out := &in.JitterSeconds, &out.JitterSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ExcludeDates != nil {
+		in, out := &in.ExcludeDates, &out.ExcludeDates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeDates != nil {
+		in, out := &in.IncludeDates, &out.IncludeDates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeCalendars != nil {
+		in, out := &in.ExcludeCalendars, &out.ExcludeCalendars
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeCalendars != nil {
+		in, out := &in.IncludeCalendars, &out.IncludeCalendars
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Job.
@@ -168,6 +603,81 @@ func (in *Job) DeepCopy() *Job {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceCondition) DeepCopyInto(out *NamespaceCondition) {
+	*out = *in
+	if in.NamespaceDefaults != nil {
+		in, out := &in.NamespaceDefaults, &out.NamespaceDefaults
+		*out = new(NamespaceDefaults)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceCondition.
+func (in *NamespaceCondition) DeepCopy() *NamespaceCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceDefaults) DeepCopyInto(out *NamespaceDefaults) {
+	*out = *in
+	if in.ExcludeDates != nil {
+		in, out := &in.ExcludeDates, &out.ExcludeDates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceDefaults.
+func (in *NamespaceDefaults) DeepCopy() *NamespaceDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProfileStatus) DeepCopyInto(out *ProfileStatus) {
+	*out = *in
+	if in.NextTime != nil {
+		in, out := &in.NextTime, &out.NextTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastProbeTime != nil {
+		in, out := &in.LastProbeTime, &out.LastProbeTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]TargetCondition, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProfileStatus.
+func (in *ProfileStatus) DeepCopy() *ProfileStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProfileStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScaleTargetRef) DeepCopyInto(out *ScaleTargetRef) {
 	*out = *in
@@ -182,3 +692,162 @@ func (in *ScaleTargetRef) DeepCopy() *ScaleTargetRef {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleTargetSelector) DeepCopyInto(out *ScaleTargetSelector) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleTargetSelector.
+func (in *ScaleTargetSelector) DeepCopy() *ScaleTargetSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleTargetSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScalingCalendar) DeepCopyInto(out *ScalingCalendar) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScalingCalendar.
+func (in *ScalingCalendar) DeepCopy() *ScalingCalendar {
+	if in == nil {
+		return nil
+	}
+	out := new(ScalingCalendar)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ScalingCalendar) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScalingCalendarList) DeepCopyInto(out *ScalingCalendarList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ScalingCalendar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScalingCalendarList.
+func (in *ScalingCalendarList) DeepCopy() *ScalingCalendarList {
+	if in == nil {
+		return nil
+	}
+	out := new(ScalingCalendarList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ScalingCalendarList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScalingCalendarSpec) DeepCopyInto(out *ScalingCalendarSpec) {
+	*out = *in
+	if in.Dates != nil {
+		in, out := &in.Dates, &out.Dates
+		*out = make([]CalendarDate, len(*in))
+		copy(*out, *in)
+	}
+	if in.ICalendars != nil {
+		in, out := &in.ICalendars, &out.ICalendars
+		*out = make([]ICalendarRef, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScalingCalendarSpec.
+func (in *ScalingCalendarSpec) DeepCopy() *ScalingCalendarSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ScalingCalendarSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScalingCalendarStatus) DeepCopyInto(out *ScalingCalendarStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]CalendarCondition, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScalingCalendarStatus.
+func (in *ScalingCalendarStatus) DeepCopy() *ScalingCalendarStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ScalingCalendarStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScalingStage) DeepCopyInto(out *ScalingStage) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScalingStage.
+func (in *ScalingStage) DeepCopy() *ScalingStage {
+	if in == nil {
+		return nil
+	}
+	out := new(ScalingStage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetCondition) DeepCopyInto(out *TargetCondition) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetCondition.
+func (in *TargetCondition) DeepCopy() *TargetCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetCondition)
+	in.DeepCopyInto(out)
+	return out
+}
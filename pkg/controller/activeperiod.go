@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	"strings"
+	"time"
+)
+
+const (
+	datePlanPrefix = "@date "
+	datePlanFormat = "2006-01-02 15:04:05"
+	// skipMessagePrefix begins the messages of the skipped executions, which are not counted in maxRuns.
+	skipMessagePrefix = "skip scaling activity"
+)
+
+// parseActiveTime parses activeFrom or activeUntil in the location, an empty value means no limit.
+func parseActiveTime(value string, location *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if location == nil {
+		location = time.Local
+	}
+	if t, err := time.ParseInLocation(isoDateFormat, value, location); err == nil {
+		return t, nil
+	}
+	return parseWindowTime(value, location)
+}
+
+// isActiveAt tells whether t is within the activeFrom and the activeUntil of the job.
+func (ch *CronJobHPA) isActiveAt(t time.Time) bool {
+	if !ch.activeFrom.IsZero() && t.Before(ch.activeFrom) {
+		return false
+	}
+	return ch.activeUntil.IsZero() || t.Before(ch.activeUntil)
+}
+
+// activeState returns the state of the job out of its active period, or an empty state if it is active.
+// requeueAfter is the time until the state is changed, 0 if it is never changed.
+func (ch *CronJobHPA) activeState(now time.Time, runCount int32) (state v1beta1.JobState, message string, requeueAfter time.Duration) {
+	if ch.maxRuns > 0 && runCount >= ch.maxRuns {
+		return v1beta1.Expired, fmt.Sprintf("cron hpa job %s has run %d times and reached maxRuns.", ch.name, runCount), 0
+	}
+	if !ch.activeUntil.IsZero() && !now.Before(ch.activeUntil) {
+		return v1beta1.Expired, fmt.Sprintf("cron hpa job %s expired at %s.", ch.name, ch.activeUntil.Format(time.RFC3339)), 0
+	}
+	// the @date job which has never run is expired after the date.
+	if date, ok := ch.planDate(); ok && runCount == 0 && now.After(date) {
+		return v1beta1.Expired, fmt.Sprintf("the date of cron hpa job %s has passed.", ch.name), 0
+	}
+	if !ch.activeFrom.IsZero() && now.Before(ch.activeFrom) {
+		return v1beta1.NotYetActive, fmt.Sprintf("cron hpa job %s is active from %s.", ch.name, ch.activeFrom.Format(time.RFC3339)), ch.activeFrom.Sub(now)
+	}
+	if !ch.activeUntil.IsZero() {
+		return "", "", ch.activeUntil.Sub(now)
+	}
+	return "", "", 0
+}
+
+// planDate returns the date of an @date plan in the timezone of the job, which is moved by the jitter and the lead time.
+func (ch *CronJobHPA) planDate() (time.Time, bool) {
+	if !strings.HasPrefix(ch.Plan, datePlanPrefix) {
+		return time.Time{}, false
+	}
+	location := ch.location
+	if location == nil {
+		location = time.Local
+	}
+	date, err := time.ParseInLocation(datePlanFormat, strings.TrimSpace(strings.TrimPrefix(ch.Plan, datePlanPrefix)), location)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date.Add(ch.offset()), true
+}
+
+// minRequeueAfter returns the earlier positive duration of the two.
+func minRequeueAfter(a, b time.Duration) time.Duration {
+	if a <= 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	log "k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+	"time"
+)
+
+// calendarDates are the dates of a ScalingCalendar or an iCalendar source.
+type calendarDates struct {
+	source string
+	dates  []v1beta1.CalendarDate
+}
+
+// loadCalendars reads the ScalingCalendars and the iCalendar sources in the namespace of the cronHPA.
+// The calendars are read when the job is built, the changes of them trigger the reconcile of the cronHPAs.
+func (ch *CronJobHPA) loadCalendars(names []string, icals []v1beta1.ICalendarRef) []calendarDates {
+	calendars := make([]calendarDates, 0, len(names)+len(icals))
+	for _, name := range names {
+		sc := &v1beta1.ScalingCalendar{}
+		if err := ch.client.Get(context.Background(), types.NamespacedName{Name: name}, sc); err != nil {
+			log.Warningf("Failed to get scalingCalendar %s of cronHPA %s in namespace %s,and skip this calendar,because of %v", name, ch.HPARef.Name, ch.HPARef.Namespace, err)
+			continue
+		}
+		calendars = append(calendars, calendarDates{source: name, dates: scalingCalendarDates(ch.client, sc, ch.location)})
+	}
+	for _, ref := range icals {
+		dates, condition := loadICalendar(context.Background(), ch.client, ch.HPARef.Namespace, ref, ch.location)
+		if !condition.Parsed {
+			log.Warningf("Failed to load iCalendar %s of cronHPA %s in namespace %s,because of %s", condition.Source, ch.HPARef.Name, ch.HPARef.Namespace, condition.Message)
+		}
+		calendars = append(calendars, calendarDates{source: "configmap " + condition.Source, dates: dates})
+	}
+	return calendars
+}
+
+// scalingCalendarDates returns the dates of the calendar and the dates imported from its iCalendar sources in the location.
+func scalingCalendarDates(c client.Reader, sc *v1beta1.ScalingCalendar, location *time.Location) []v1beta1.CalendarDate {
+	dates := append([]v1beta1.CalendarDate{}, sc.Spec.Dates...)
+	for _, ref := range sc.Spec.ICalendars {
+		icalDates, condition := loadICalendar(context.Background(), c, ref.Namespace, ref, location)
+		if !condition.Parsed {
+			log.Warningf("Failed to load iCalendar %s of scalingCalendar %s,because of %s", condition.Source, sc.Name, condition.Message)
+		}
+		dates = append(dates, icalDates...)
+	}
+	return dates
+}
+
+// matchCalendars returns the first date of the calendars which matches the day of now.
+func matchCalendars(calendars []calendarDates, now time.Time) (calendar string, date v1beta1.CalendarDate, found bool) {
+	for _, c := range calendars {
+		for _, d := range c.dates {
+			matched, err := matchExcludeDate(strings.TrimSpace(d.Date), now)
+			if err != nil {
+				log.Warningf("Failed to parse date %s in calendar %s,and skip this date,because of %v", d.Date, c.source, err)
+				continue
+			}
+			if matched {
+				return c.source, d, true
+			}
+		}
+	}
+	return "", v1beta1.CalendarDate{}, false
+}
+
+// isIncluded tells whether the day of now is in the includeDates or the includeCalendars,
+// which overrides the exclusions of the day.
+func (ch *CronJobHPA) isIncluded(now time.Time) bool {
+	if included, date := IsTodayIncluded(ch.includeDates, now); included {
+		log.Infof("cronHPA job %s of cronHPA %s in namespace %s is not excluded,because of includeDate (%s)", ch.name, ch.HPARef.Name, ch.HPARef.Namespace, date)
+		return true
+	}
+	calendar, date, found := matchCalendars(ch.includeCalendarDates, now)
+	if found {
+		log.Infof("cronHPA job %s of cronHPA %s in namespace %s is not excluded,because of includeCalendar %s (%s)", ch.name, ch.HPARef.Name, ch.HPARef.Namespace, calendar, calendarDateString(date))
+	}
+	return found
+}
+
+// includedDates returns the includeDates and the dates of the include calendars.
+func (ch *CronJobHPA) includedDates() []string {
+	dates := append([]string{}, ch.includeDates...)
+	for _, c := range ch.includeCalendarDates {
+		for _, d := range c.dates {
+			dates = append(dates, d.Date)
+		}
+	}
+	return dates
+}
+
+// isExcludedByCalendars tells whether the day of now is in the excludeCalendars.
+func (ch *CronJobHPA) isExcludedByCalendars(now time.Time) (bool, string) {
+	calendar, date, found := matchCalendars(ch.excludeCalendarDates, now)
+	if !found {
+		return false, ""
+	}
+	return true, fmt.Sprintf("skip scaling activity,because of excludeCalendar %s (%s).", calendar, calendarDateString(date))
+}
+
+func calendarDateString(date v1beta1.CalendarDate) string {
+	if date.Label == "" {
+		return date.Date
+	}
+	return fmt.Sprintf("%s: %s", date.Label, date.Date)
+}
+
+// usesCalendar tells whether the calendars of a cronHPA or of one of its jobs include the ScalingCalendar.
+func usesCalendar(excludeCalendars []string, includeCalendars []string, jobs []v1beta1.Job, name string) bool {
+	names := append(append([]string{}, excludeCalendars...), includeCalendars...)
+	for _, job := range jobs {
+		names = append(append(names, job.ExcludeCalendars...), job.IncludeCalendars...)
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeUnique returns the global values and the values of the job without duplicates.
+func mergeUnique(global []string, job []string) []string {
+	merged := make([]string, 0, len(global)+len(job))
+	seen := make(map[string]bool)
+	for _, name := range append(append([]string{}, global...), job...) {
+		if !seen[name] {
+			seen[name] = true
+			merged = append(merged, name)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"context"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"testing"
+	"time"
+)
+
+// calendarClient serves the ScalingCalendars from the cache and counts the reads.
+type calendarClient struct {
+	client.Client
+	calendars map[string][]v1beta1.CalendarDate
+	reads     int
+}
+
+func (c *calendarClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	c.reads++
+	sc := obj.(*v1beta1.ScalingCalendar)
+	sc.Name = key.Name
+	sc.Spec.Dates = c.calendars[key.Name]
+	return nil
+}
+
+// recordingExecutor records the jobs which are added and updated.
+type recordingExecutor struct {
+	CronExecutor
+	added   int
+	updated int
+}
+
+func (e *recordingExecutor) AddJob(job CronJob) error { e.added++; return nil }
+func (e *recordingExecutor) Update(job CronJob) error { e.updated++; return nil }
+
+func newCalendarCronHPA() *v1beta1.CronHorizontalPodAutoscaler {
+	return &v1beta1.CronHorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "calendar"},
+		Spec: v1beta1.CronHorizontalPodAutoscalerSpec{
+			ScaleTargetRef:   v1beta1.ScaleTargetRef{ApiVersion: "apps/v1", Kind: "Deployment", Name: "nginx"},
+			IncludeCalendars: []string{"launches"},
+		},
+	}
+}
+
+func buildCalendarJob(t *testing.T, c client.Client) *CronJobHPA {
+	t.Helper()
+	j, err := CronHPAJobFactory(newCalendarCronHPA(), nil, v1beta1.Job{Name: "weekdays", Schedule: "0 0 8 * * 1-5", TargetSize: 3}, nil, nil, nil, c)
+	if err != nil {
+		t.Fatalf("CronHPAJobFactory() error = %v", err)
+	}
+	return j.(*CronJobHPA)
+}
+
+func TestIncludeScheduleDoesNotReadCalendars(t *testing.T) {
+	c := &calendarClient{calendars: map[string][]v1beta1.CalendarDate{"launches": {{Date: "2026-10-17"}}}}
+	job := buildCalendarJob(t, c)
+	schedule, err := job.Schedule()
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+
+	reads := c.reads
+	// 2026-10-16 is a Friday, the calendar includes the Saturday.
+	from := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	for i := 0; i < 100; i++ {
+		if next := schedule.Next(from); !next.Equal(time.Date(2026, 10, 17, 8, 0, 0, 0, time.Local)) {
+			t.Fatalf("Next() = %v, want the included Saturday", next)
+		}
+	}
+	if c.reads != reads {
+		t.Errorf("Next() reads the calendars %d times", c.reads-reads)
+	}
+}
+
+func TestIncludeCalendarChangeUpdatesJob(t *testing.T) {
+	c := &calendarClient{calendars: map[string][]v1beta1.CalendarDate{"launches": {{Date: "2026-10-17"}}}}
+	executor := &recordingExecutor{}
+	cm := &CronManager{registry: newJobRegistry(), cronExecutor: executor}
+	if err := cm.createOrUpdate(buildCalendarJob(t, c)); err != nil {
+		t.Fatalf("createOrUpdate() error = %v", err)
+	}
+
+	// the same calendar doesn't update the job.
+	if _, ok := cm.createOrUpdate(buildCalendarJob(t, c)).(*NoNeedUpdate); !ok {
+		t.Errorf("createOrUpdate() of the same calendar updates the job")
+	}
+
+	// the reconcile triggered by the changed calendar builds the job with the new dates.
+	c.calendars["launches"] = []v1beta1.CalendarDate{{Date: "2026-10-18"}}
+	job := buildCalendarJob(t, c)
+	if err := cm.createOrUpdate(job); err != nil {
+		t.Fatalf("createOrUpdate() error = %v", err)
+	}
+	if executor.added != 1 || executor.updated != 1 {
+		t.Errorf("the job is added %d times and updated %d times, want 1 and 1", executor.added, executor.updated)
+	}
+	schedule, err := job.Schedule()
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+	if next := schedule.Next(time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)); !next.Equal(time.Date(2026, 10, 18, 8, 0, 0, 0, time.Local)) {
+		t.Errorf("Next() = %v, want the newly included Sunday", next)
+	}
+}
+
+func TestUsesCalendar(t *testing.T) {
+	jobs := []v1beta1.Job{{Name: "scale-up", ExcludeCalendars: []string{"maintenance"}}}
+	if !usesCalendar(nil, []string{"launches"}, jobs, "launches") || !usesCalendar(nil, nil, jobs, "maintenance") {
+		t.Errorf("usesCalendar() doesn't find the calendars of the cronHPA and the jobs")
+	}
+	if usesCalendar([]string{"holidays"}, nil, jobs, "launches") {
+		t.Errorf("usesCalendar() finds a calendar which is not used")
+	}
+}
+
+func TestExcludeCalendarChangeUpdatesJob(t *testing.T) {
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.Local)
+	c := &calendarClient{calendars: map[string][]v1beta1.CalendarDate{"maintenance": {{Date: "2026-10-19", Label: "freeze"}}}}
+	build := func() *CronJobHPA {
+		instance := newCalendarCronHPA()
+		instance.Spec.IncludeCalendars = nil
+		j, err := CronHPAJobFactory(instance, nil, v1beta1.Job{Name: "scale-up", Schedule: "0 0 8 * * *", TargetSize: 3, ExcludeCalendars: []string{"maintenance"}}, nil, nil, nil, c)
+		if err != nil {
+			t.Fatalf("CronHPAJobFactory() error = %v", err)
+		}
+		return j.(*CronJobHPA)
+	}
+
+	executor := &recordingExecutor{}
+	cm := &CronManager{registry: newJobRegistry(), cronExecutor: executor}
+	job := build()
+	if err := cm.createOrUpdate(job); err != nil {
+		t.Fatalf("createOrUpdate() error = %v", err)
+	}
+	reads := c.reads
+	if skip, msg := job.isExcludedByCalendars(now); !skip || msg != "skip scaling activity,because of excludeCalendar maintenance (freeze: 2026-10-19)." {
+		t.Errorf("isExcludedByCalendars() = %v, %q", skip, msg)
+	}
+	if c.reads != reads {
+		t.Errorf("the execution check reads the calendars %d times", c.reads-reads)
+	}
+
+	// the freeze is moved, the job is updated without a restart and runs on the day.
+	c.calendars["maintenance"] = []v1beta1.CalendarDate{{Date: "2026-10-20", Label: "freeze"}}
+	job = build()
+	if err := cm.createOrUpdate(job); err != nil {
+		t.Fatalf("createOrUpdate() error = %v", err)
+	}
+	if executor.updated != 1 {
+		t.Errorf("the job is updated %d times, want 1", executor.updated)
+	}
+	registered, _ := cm.registry.get(job.ID())
+	if skip, _ := registered.isExcludedByCalendars(now); skip {
+		t.Errorf("the registered job still skips the day which is removed from the calendar")
+	}
+	if skip, _ := registered.isExcludedByCalendars(now.AddDate(0, 0, 1)); !skip {
+		t.Errorf("the registered job doesn't skip the day which is added to the calendar")
+	}
+}
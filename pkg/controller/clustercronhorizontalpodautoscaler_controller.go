@@ -0,0 +1,354 @@
+/*
+Copyright 2018 zhongwei.lzw@alibaba-inc.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	log "k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sort"
+	"time"
+)
+
+const clusterPolicyOptOutAll = "*"
+
+// NewClusterReconciler returns the reconciler of ClusterCronHorizontalPodAutoscaler.
+// It shares the CronManager with the cronHPA reconciler.
+func NewClusterReconciler(mgr manager.Manager, cm *CronManager) *ReconcileClusterCronHorizontalPodAutoscaler {
+	return &ReconcileClusterCronHorizontalPodAutoscaler{Client: mgr.GetClient(), CronManager: cm}
+}
+
+var _ reconcile.Reconciler = &ReconcileClusterCronHorizontalPodAutoscaler{}
+
+// ReconcileClusterCronHorizontalPodAutoscaler reconciles a ClusterCronHorizontalPodAutoscaler object
+type ReconcileClusterCronHorizontalPodAutoscaler struct {
+	client.Client
+	CronManager *CronManager
+}
+
+// SetupWithManager watches the cluster policies, the namespaces they select, the cronHPAs
+// which opt out of or override them and the ScalingCalendars they use.
+func (r *ReconcileClusterCronHorizontalPodAutoscaler) SetupWithManager(mgr manager.Manager) error {
+	namespaceChanged := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return !labels.Equals(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels()) || namespaceDefaultsChanged(e.ObjectOld, e.ObjectNew)
+		},
+	}
+	optOutChanged := predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldCronHPA, ok := e.ObjectOld.(*v1beta1.CronHorizontalPodAutoscaler)
+			if !ok {
+				return false
+			}
+			newCronHPA, ok := e.ObjectNew.(*v1beta1.CronHorizontalPodAutoscaler)
+			if !ok {
+				return false
+			}
+			return !apiequality.Semantic.DeepEqual(oldCronHPA.Spec.ClusterPolicyOptOut, newCronHPA.Spec.ClusterPolicyOptOut)
+		},
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1beta1.ClusterCronHorizontalPodAutoscaler{}).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, handler.EnqueueRequestsFromMapFunc(r.mapToClusterPolicies), builder.WithPredicates(namespaceChanged)).
+		Watches(&source.Kind{Type: &v1beta1.CronHorizontalPodAutoscaler{}}, handler.EnqueueRequestsFromMapFunc(r.mapToClusterPolicies), builder.WithPredicates(optOutChanged)).
+		Watches(&source.Kind{Type: &v1beta1.ScalingCalendar{}}, handler.EnqueueRequestsFromMapFunc(r.mapCalendarToClusterPolicies)).
+		Complete(r)
+}
+
+// mapCalendarToClusterPolicies returns the cluster policies which use the ScalingCalendar.
+func (r *ReconcileClusterCronHorizontalPodAutoscaler) mapCalendarToClusterPolicies(obj client.Object) []reconcile.Request {
+	list := &v1beta1.ClusterCronHorizontalPodAutoscalerList{}
+	if err := r.List(context.Background(), list); err != nil {
+		log.Errorf("Failed to list clusterCronHPAs,because of %v", err)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0)
+	for _, item := range list.Items {
+		if usesCalendar(item.Spec.ExcludeCalendars, item.Spec.IncludeCalendars, item.Spec.Jobs, obj.GetName()) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: item.Name}})
+		}
+	}
+	return requests
+}
+
+func (r *ReconcileClusterCronHorizontalPodAutoscaler) mapToClusterPolicies(obj client.Object) []reconcile.Request {
+	list := &v1beta1.ClusterCronHorizontalPodAutoscalerList{}
+	if err := r.List(context.Background(), list); err != nil {
+		log.Errorf("Failed to list clusterCronHPAs,because of %v", err)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, item := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: item.Name}})
+	}
+	return requests
+}
+
+// +kubebuilder:rbac:groups=autoscaling.alibabacloud.com,resources=clustercronhorizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+func (r *ReconcileClusterCronHorizontalPodAutoscaler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	log.Infof("Start to handle clusterCronHPA %s", request.Name)
+	instance := &v1beta1.ClusterCronHorizontalPodAutoscaler{}
+	err := r.Get(ctx, request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			log.Infof("Remove the jobs of clusterCronHPA %s, because it is not found", request.Name)
+			r.CronManager.deleteClusterPolicyJobs(request.Name)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	namespaces, err := r.selectNamespaces(ctx, instance)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	optedOut, err := r.optedOutNamespaces(ctx, instance.Name)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// check scaleTargetSelector, excludeWindows, calendars and timezone,
+	// excludeDates are updated in the jobs which they affect.
+	globalChanged := !apiequality.Semantic.DeepEqual(instance.Status.ScaleTargetSelector, instance.Spec.ScaleTargetSelector) ||
+		!apiequality.Semantic.DeepEqual(instance.Status.ExcludeWindows, instance.Spec.ExcludeWindows) ||
+		!apiequality.Semantic.DeepEqual(instance.Status.ExcludeCalendars, instance.Spec.ExcludeCalendars) ||
+		!apiequality.Semantic.DeepEqual(instance.Status.IncludeCalendars, instance.Spec.IncludeCalendars) ||
+		instance.Status.Timezone != instance.Spec.Timezone
+
+	previous := make(map[string]v1beta1.NamespaceCondition)
+	for _, n := range instance.Status.Namespaces {
+		previous[n.Namespace] = n
+	}
+
+	// requeue when a job becomes active or expires.
+	var requeueAfter time.Duration
+	status := v1beta1.ClusterCronHorizontalPodAutoscalerStatus{
+		ScaleTargetSelector: instance.Spec.ScaleTargetSelector,
+		ExcludeDates:        instance.Spec.ExcludeDates,
+		ExcludeWindows:      instance.Spec.ExcludeWindows,
+		ExcludeCalendars:    instance.Spec.ExcludeCalendars,
+		IncludeCalendars:    instance.Spec.IncludeCalendars,
+		Timezone:            instance.Spec.Timezone,
+		Namespaces:          make([]v1beta1.NamespaceCondition, 0, len(namespaces)),
+	}
+	for _, ns := range namespaces {
+		namespace := ns.Name
+		prev := previous[namespace]
+		delete(previous, namespace)
+		if optedOut[namespace] {
+			r.deleteJobs(instance.Name, prev.Conditions)
+			status.Namespaces = append(status.Namespaces, v1beta1.NamespaceCondition{Namespace: namespace, OptedOut: true})
+			continue
+		}
+		defaults := parseNamespaceDefaults(&ns)
+		changed := globalChanged || !apiequality.Semantic.DeepEqual(withoutExcludeDates(prev.NamespaceDefaults), withoutExcludeDates(defaults))
+		conditions, after := r.syncNamespaceJobs(instance, namespace, defaults, prev.Conditions, changed)
+		requeueAfter = minRequeueAfter(requeueAfter, after)
+		status.Namespaces = append(status.Namespaces, v1beta1.NamespaceCondition{
+			Namespace:         namespace,
+			NamespaceDefaults: defaults,
+			Conditions:        conditions,
+		})
+	}
+
+	// the namespaces which are not selected any more.
+	for _, n := range previous {
+		r.deleteJobs(instance.Name, n.Conditions)
+	}
+	// the jobs which are not in the conditions, such as the old jobs of the changed specs whose conditions are lost.
+	keep := make(map[string]bool)
+	for _, n := range status.Namespaces {
+		for _, c := range n.Conditions {
+			keep[c.JobId] = true
+		}
+	}
+	r.CronManager.deleteJobsExcept(types.NamespacedName{Name: instance.Name}, func(job *CronJobHPA) bool {
+		return true
+	}, keep)
+
+	if !apiequality.Semantic.DeepEqual(instance.Status, status) {
+		instance.Status = status
+		if err := r.Update(ctx, instance); err != nil {
+			log.Errorf("Failed to update clusterCronHPA %s status, because of %v", instance.Name, err)
+			return reconcile.Result{}, err
+		}
+	}
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// syncNamespaceJobs submits the jobs of the cluster policy in one namespace and returns the conditions
+// of them in the order of the spec, and the time until a job becomes active or expires.
+func (r *ReconcileClusterCronHorizontalPodAutoscaler) syncNamespaceJobs(instance *v1beta1.ClusterCronHorizontalPodAutoscaler, namespace string, defaults *v1beta1.NamespaceDefaults, conditions []v1beta1.Condition, globalChanged bool) ([]v1beta1.Condition, time.Duration) {
+	left := make(map[string]v1beta1.Condition)
+	for _, c := range conditions {
+		left[c.Name] = c
+	}
+
+	var requeueAfter time.Duration
+	result := make([]v1beta1.Condition, 0, len(instance.Spec.Jobs))
+	for _, job := range instance.Spec.Jobs {
+		c, exists := left[job.Name]
+		delete(left, job.Name)
+		// schedule, RunOnce or targetSize has changed
+		if exists && (globalChanged || c.Schedule != job.Schedule || c.RunOnce != job.RunOnce || c.TargetSize != job.TargetSize) {
+			r.deleteJobs(instance.Name, []v1beta1.Condition{c})
+			exists = false
+		}
+
+		jobCondition := v1beta1.Condition{
+			Name:          job.Name,
+			Schedule:      job.Schedule,
+			RunOnce:       job.RunOnce,
+			TargetSize:    job.TargetSize,
+			RunCount:      c.RunCount,
+			ReadyDuration: c.ReadyDuration,
+			LastProbeTime: metav1.Time{Time: time.Now()},
+		}
+		j, err := ClusterCronHPAJobFactory(instance, namespace, defaults, job, r.CronManager.scaler, r.CronManager.mapper, r.CronManager.dynamicClient, r.CronManager.jobClient)
+		if err != nil {
+			jobCondition.State = v1beta1.Failed
+			jobCondition.Message = fmt.Sprintf("Failed to create cron hpa job %s of clusterCronHPA %s in namespace %s,because of %v",
+				job.Name, instance.Name, namespace, err)
+			log.Errorf("Failed to create cron hpa job %s,because of %v", job.Name, err)
+			result = append(result, jobCondition)
+			continue
+		}
+		r.CronManager.applyJitter(j)
+		j.(*CronJobHPA).setReadyDuration(c.ReadyDuration)
+		jobCondition.NormalizedSchedule = j.SchedulePlan()
+		jobCondition.JitterOffset = int32(j.(*CronJobHPA).jitter / time.Second)
+
+		if exists {
+			// run once and keep the condition when reaches the final state
+			if runOnce(job) && (c.State == v1beta1.Succeed || c.State == v1beta1.Failed) {
+				r.deleteJobs(instance.Name, []v1beta1.Condition{c})
+				result = append(result, c)
+				continue
+			}
+		}
+
+		// the job out of its active period is removed from the engine.
+		state, message, after := j.(*CronJobHPA).activeState(time.Now(), c.RunCount)
+		requeueAfter = minRequeueAfter(requeueAfter, after)
+		if state != "" {
+			r.deleteJobs(instance.Name, []v1beta1.Condition{c})
+			if exists && c.State == state && c.Message == message {
+				result = append(result, c)
+				continue
+			}
+			jobCondition.State = state
+			jobCondition.Message = message
+			result = append(result, jobCondition)
+			continue
+		}
+
+		jobCondition.JobId = j.ID()
+		err = r.CronManager.createOrUpdate(j)
+		_, noNeedUpdate := err.(*NoNeedUpdate)
+		switch {
+		case noNeedUpdate && exists:
+			result = append(result, c)
+			continue
+		case err != nil && !noNeedUpdate:
+			jobCondition.State = v1beta1.Failed
+			jobCondition.Message = fmt.Sprintf("Failed to update cron hpa job %s,because of %v", job.Name, err)
+		default:
+			// the job is submitted, or registered before and its condition is lost.
+			jobCondition.State = v1beta1.Submitted
+			j.(*CronJobHPA).setFireTimes(&jobCondition, time.Now())
+		}
+		result = append(result, jobCondition)
+	}
+
+	// the jobs which are removed from the spec.
+	for _, c := range left {
+		r.deleteJobs(instance.Name, []v1beta1.Condition{c})
+	}
+	return result, requeueAfter
+}
+
+func (r *ReconcileClusterCronHorizontalPodAutoscaler) deleteJobs(name string, conditions []v1beta1.Condition) {
+	for _, c := range conditions {
+		if c.JobId == "" {
+			continue
+		}
+		if err := r.CronManager.delete(c.JobId); err != nil {
+			log.Errorf("Failed to delete job %s of clusterCronHPA %s, because of %v", c.Name, name, err)
+		}
+	}
+}
+
+// selectNamespaces returns the namespaces selected by the policy sorted by name.
+func (r *ReconcileClusterCronHorizontalPodAutoscaler) selectNamespaces(ctx context.Context, instance *v1beta1.ClusterCronHorizontalPodAutoscaler) ([]corev1.Namespace, error) {
+	selector := labels.Everything()
+	if instance.Spec.NamespaceSelector != nil {
+		var err error
+		selector, err = metav1.LabelSelectorAsSelector(instance.Spec.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespaceSelector in clusterCronHPA %s, because of %v", instance.Name, err)
+		}
+	}
+	list := &corev1.NamespaceList{}
+	if err := r.List(ctx, list, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list namespaces, because of %v", err)
+	}
+	namespaces := make([]corev1.Namespace, 0, len(list.Items))
+	for _, ns := range list.Items {
+		if ns.DeletionTimestamp != nil {
+			continue
+		}
+		namespaces = append(namespaces, ns)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Name < namespaces[j].Name
+	})
+	return namespaces, nil
+}
+
+// optedOutNamespaces returns the namespaces in which a cronHPA opts out of the policy.
+func (r *ReconcileClusterCronHorizontalPodAutoscaler) optedOutNamespaces(ctx context.Context, name string) (map[string]bool, error) {
+	list := &v1beta1.CronHorizontalPodAutoscalerList{}
+	if err := r.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("failed to list cronHPAs, because of %v", err)
+	}
+	optedOut := make(map[string]bool)
+	for _, cronHPA := range list.Items {
+		for _, policy := range cronHPA.Spec.ClusterPolicyOptOut {
+			if policy == name || policy == clusterPolicyOptOutAll {
+				optedOut[cronHPA.Namespace] = true
+			}
+		}
+	}
+	return optedOut, nil
+}
@@ -30,16 +30,27 @@ type CronExecutor interface {
 
 type CronHPAExecutor struct {
 	Engine *cron.Cron
+	queue  *executionQueue
 }
 
 func (ce *CronHPAExecutor) AddJob(job CronJob) error {
-	err := ce.Engine.AddJob(job.SchedulePlan(), job)
+	err := ce.schedule(job)
 	if err != nil {
 		log.Errorf("Failed to add job to engine,because of %v", err)
 	}
 	return err
 }
 
+// schedule adds the job to the engine with the schedule built by the job.
+func (ce *CronHPAExecutor) schedule(job CronJob) error {
+	schedule, err := job.Schedule()
+	if err != nil {
+		return err
+	}
+	ce.Engine.Schedule(schedule, &queuedJob{CronJob: job, queue: ce.queue})
+	return nil
+}
+
 func (ce *CronHPAExecutor) ListEntries() []*cron.Entry {
 	entries := ce.Engine.Entries()
 	return entries
@@ -49,20 +60,32 @@ func (ce *CronHPAExecutor) FindJob(job CronJob) (bool, FailedFindJobReason) {
 	entries := ce.Engine.Entries()
 	for _, e := range entries {
 		if e.Job.ID() == job.ID() {
-			// clean up out of date jobs when it reached maxOutOfDateTimeout
-			if e.Next.Add(maxOutOfDateTimeout).After(time.Now()) {
-				return true, ""
-			}
-			log.Warningf("The job %s(job id %s) in cronhpa %s namespace %s is out of date.", job.Name(), job.ID(), job.CronHPAMeta().Name, job.CronHPAMeta().Namespace)
-			return false, JobTimeOut
+			return checkEntry(e, job)
 		}
 	}
 	return false, ""
 }
 
+// checkEntry checks the entry of the job in the engine, the entry is nil if the job is not in the engine.
+func checkEntry(e *cron.Entry, job CronJob) (bool, FailedFindJobReason) {
+	if e == nil {
+		return false, ""
+	}
+	// the schedule has no time left, such as a schedule of a past year, so the job is finished instead of out of date.
+	if e.Next.IsZero() {
+		return true, ""
+	}
+	// clean up out of date jobs when it reached maxOutOfDateTimeout
+	if e.Next.Add(maxOutOfDateTimeout).After(time.Now()) {
+		return true, ""
+	}
+	log.Warningf("The job %s(job id %s) in cronhpa %s namespace %s is out of date.", job.Name(), job.ID(), job.CronHPAMeta().Name, job.CronHPAMeta().Namespace)
+	return false, JobTimeOut
+}
+
 func (ce *CronHPAExecutor) Update(job CronJob) error {
 	ce.Engine.RemoveJob(job.ID())
-	err := ce.Engine.AddJob(job.SchedulePlan(), job)
+	err := ce.schedule(job)
 	if err != nil {
 		log.Errorf("Failed to update job to engine,because of %v", err)
 	}
@@ -82,13 +105,21 @@ func (ce *CronHPAExecutor) Stop() {
 	ce.Engine.Stop()
 }
 
-func NewCronHPAExecutor(timezone *time.Location, handler func(job *cron.JobResult)) CronExecutor {
+// NewCronHPAExecutor returns the executor whose due jobs run in the queue.
+func NewCronHPAExecutor(timezone *time.Location, handler func(job *cron.JobResult), queue *executionQueue) CronExecutor {
 	if timezone == nil {
 		timezone = time.Now().Location()
 	}
 	c := &CronHPAExecutor{
 		Engine: cron.NewWithLocation(timezone),
+		queue:  queue,
 	}
-	c.Engine.AddResultHandler(handler)
+	// the handler receives the job instead of the wrapper of the queue.
+	c.Engine.AddResultHandler(func(js *cron.JobResult) {
+		if qj, ok := js.Ref.(*queuedJob); ok {
+			js.Ref = qj.CronJob
+		}
+		handler(js)
+	})
 	return c
 }
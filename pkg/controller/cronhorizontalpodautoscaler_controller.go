@@ -21,14 +21,23 @@ import (
 	"fmt"
 	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
 	autoscalingv1beta1 "github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	log "k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	"strings"
 	"time"
 )
@@ -39,10 +48,14 @@ import (
  */
 
 // newReconciler returns a new reconcile.Reconciler
-func NewReconciler(mgr manager.Manager) reconcile.Reconciler {
+func NewReconciler(mgr manager.Manager, options CronManagerOptions) *ReconcileCronHorizontalPodAutoscaler {
 	var stopChan chan struct{}
-	cm := NewCronManager(mgr.GetConfig(), mgr.GetClient(), mgr.GetEventRecorderFor("CronHorizontalPodAutoscaler"))
+	cm := NewCronManager(mgr.GetConfig(), mgr.GetClient(), mgr.GetAPIReader(), mgr.GetEventRecorderFor("CronHorizontalPodAutoscaler"), options)
 	r := &ReconcileCronHorizontalPodAutoscaler{Client: mgr.GetClient(), scheme: mgr.GetScheme(), CronManager: cm}
+	// the kinds of the CRDs which are installed or changed later are found without a restart.
+	if err := cm.restMapper.watchCRDs(mgr); err != nil {
+		log.Warningf("Failed to watch CRDs and the discovery cache is only reset when a kind is not found,because of %v", err)
+	}
 	go func(cronManager *CronManager, stopChan chan struct{}) {
 		cm.Run(stopChan)
 		<-stopChan
@@ -57,6 +70,94 @@ func NewReconciler(mgr manager.Manager) reconcile.Reconciler {
 
 var _ reconcile.Reconciler = &ReconcileCronHorizontalPodAutoscaler{}
 
+// SetupWithManager watches the cronHPAs, the namespace annotations which supply their defaults,
+// the ScalingCalendars and the ConfigMaps of their iCalendar sources. Only the metadata of the ConfigMaps is watched and cached.
+func (r *ReconcileCronHorizontalPodAutoscaler) SetupWithManager(mgr manager.Manager) error {
+	annotationsChanged := predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return namespaceDefaultsChanged(e.ObjectOld, e.ObjectNew)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&autoscalingv1beta1.CronHorizontalPodAutoscaler{}).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, handler.EnqueueRequestsFromMapFunc(r.mapToCronHPAs), builder.WithPredicates(annotationsChanged)).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.mapConfigMapToCronHPAs), builder.OnlyMetadata).
+		Watches(&source.Kind{Type: &v1beta1.ScalingCalendar{}}, handler.EnqueueRequestsFromMapFunc(r.mapCalendarToCronHPAs)).
+		Complete(r)
+}
+
+// mapCalendarToCronHPAs returns the cronHPAs which use the ScalingCalendar, so their jobs are built again with its dates.
+// The calendar is also changed by its status when a ConfigMap of its iCalendar sources is changed.
+func (r *ReconcileCronHorizontalPodAutoscaler) mapCalendarToCronHPAs(obj client.Object) []reconcile.Request {
+	list := &autoscalingv1beta1.CronHorizontalPodAutoscalerList{}
+	if err := r.List(context.Background(), list); err != nil {
+		log.Errorf("Failed to list cronHPAs,because of %v", err)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0)
+	for _, item := range list.Items {
+		if usesCalendar(item.Spec.ExcludeCalendars, item.Spec.IncludeCalendars, item.Spec.Jobs, obj.GetName()) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: item.Namespace, Name: item.Name}})
+		}
+	}
+	return requests
+}
+
+func (r *ReconcileCronHorizontalPodAutoscaler) mapToCronHPAs(obj client.Object) []reconcile.Request {
+	list := &autoscalingv1beta1.CronHorizontalPodAutoscalerList{}
+	if err := r.List(context.Background(), list, client.InNamespace(obj.GetName())); err != nil {
+		log.Errorf("Failed to list cronHPAs in namespace %s,because of %v", obj.GetName(), err)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, item := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: item.Namespace, Name: item.Name}})
+	}
+	return requests
+}
+
+// mapConfigMapToCronHPAs returns the cronHPAs in the namespace of the ConfigMap which use it as an iCalendar source.
+func (r *ReconcileCronHorizontalPodAutoscaler) mapConfigMapToCronHPAs(obj client.Object) []reconcile.Request {
+	list := &autoscalingv1beta1.CronHorizontalPodAutoscalerList{}
+	if err := r.List(context.Background(), list, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.Errorf("Failed to list cronHPAs in namespace %s,because of %v", obj.GetNamespace(), err)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0)
+	for _, item := range list.Items {
+		for _, ref := range append(append([]v1beta1.ICalendarRef{}, item.Spec.ExcludeICalendars...), item.Spec.IncludeICalendars...) {
+			if ref.Name == obj.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: item.Namespace, Name: item.Name}})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// iCalendarConditions returns the conditions of the iCalendar sources of the cronHPA, which are parsed
+// in the timezone of its jobs.
+func iCalendarConditions(ctx context.Context, c client.Reader, instance *v1beta1.CronHorizontalPodAutoscaler, defaults *v1beta1.NamespaceDefaults) []v1beta1.CalendarCondition {
+	refs := append(append([]v1beta1.ICalendarRef{}, instance.Spec.ExcludeICalendars...), instance.Spec.IncludeICalendars...)
+	if len(refs) == 0 {
+		return nil
+	}
+	// the invalid timezone is reported by the jobs.
+	location, _ := loadTimezone(instance.Spec.Timezone, defaults)
+	conditions := make([]v1beta1.CalendarCondition, 0, len(refs))
+	for _, ref := range refs {
+		_, condition := loadICalendar(ctx, c, instance.Namespace, ref, location)
+		conditions = append(conditions, condition)
+	}
+	return conditions
+}
+
 // ReconcileCronHorizontalPodAutoscaler reconciles a CronHorizontalPodAutoscaler object
 type ReconcileCronHorizontalPodAutoscaler struct {
 	client.Client
@@ -71,6 +172,7 @@ type ReconcileCronHorizontalPodAutoscaler struct {
 // Automatically generate RBAC rules to allow the Controller to read and write Deployments
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=autoscaling.alibabacloud.com,resources=cronhorizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch
 func (r *ReconcileCronHorizontalPodAutoscaler) Reconcile(context context.Context, request reconcile.Request) (reconcile.Result, error) {
 	// Fetch the CronHorizontalPodAutoscaler instance
 	log.Infof("Start to handle cronHPA %s in %s namespace", request.Name, request.Namespace)
@@ -79,9 +181,9 @@ func (r *ReconcileCronHorizontalPodAutoscaler) Reconcile(context context.Context
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Object not found, return.  Created objects are automatically garbage collected.
-			// For additional cleanup logic use finalizers.
-			log.Infof("GC start for: cronHPA %s in %s namespace is not found", request.Name, request.Namespace)
-			go r.CronManager.GC()
+			// The jobs of the deleted object are removed at once.
+			log.Infof("Remove the jobs of cronHPA %s in %s namespace which is not found", request.Name, request.Namespace)
+			r.CronManager.deleteOwnerJobs(request.NamespacedName)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
@@ -91,18 +193,41 @@ func (r *ReconcileCronHorizontalPodAutoscaler) Reconcile(context context.Context
 	//log.Infof("%v is handled by cron-hpa controller", instance.Name)
 	conditions := instance.Status.Conditions
 
+	defaults, err := getNamespaceDefaults(context, r.Client, instance.Namespace)
+	if err != nil {
+		log.Errorf("Failed to get defaults of namespace %s, because of %v", instance.Namespace, err)
+		return reconcile.Result{}, err
+	}
+
+	// the runs are counted across the updates of the jobs.
+	runCounts := make(map[string]int32)
+	// the lead times are adapted to the readiness measured on the previous runs.
+	readyDurations := make(map[string]string)
+	for _, c := range conditions {
+		runCounts[c.Name] = c.RunCount
+		readyDurations[c.Name] = c.ReadyDuration
+	}
+
 	leftConditions := make([]v1beta1.Condition, 0)
-	// check scaleTargetRef and excludeDates
-	if checkGlobalParamsChanges(instance.Status, instance.Spec) {
+	// check scaleTargetRef and the other global params, excludeDates are updated in the jobs which they affect.
+	if checkGlobalParamsChanges(instance.Status, instance.Spec, defaults) {
 		for _, cJob := range conditions {
 			err := r.CronManager.delete(cJob.JobId)
 			if err != nil {
 				log.Errorf("Failed to delete job %s in cronHPA %s namespace %s, because of %v", cJob.Name, instance.Name, instance.Namespace, err)
 			}
 		}
-		// update scaleTargetRef and excludeDates
+		// update scaleTargetRef and the other global params
 		instance.Status.ScaleTargetRef = instance.Spec.ScaleTargetRef
-		instance.Status.ExcludeDates = instance.Spec.ExcludeDates
+		instance.Status.ScaleTargetRefs = instance.Spec.ScaleTargetRefs
+		instance.Status.ScaleTargetSelector = instance.Spec.ScaleTargetSelector
+		instance.Status.Distribution = instance.Spec.Distribution
+		instance.Status.Stages = instance.Spec.Stages
+		instance.Status.ScaleDownOrder = instance.Spec.ScaleDownOrder
+		instance.Status.ExcludeWindows = instance.Spec.ExcludeWindows
+		instance.Status.ExcludeCalendars = instance.Spec.ExcludeCalendars
+		instance.Status.IncludeCalendars = instance.Spec.IncludeCalendars
+		instance.Status.Timezone = instance.Spec.Timezone
 	} else {
 		// check status and delete the expired job
 		for _, cJob := range conditions {
@@ -147,6 +272,17 @@ func (r *ReconcileCronHorizontalPodAutoscaler) Reconcile(context context.Context
 	leftConditionsMap := convertConditionMaps(leftConditions)
 
 	noNeedUpdateStatus := true
+	// requeue when a job becomes active or expires.
+	var requeueAfter time.Duration
+	// the jobs whose excludeDates are changed are updated by createOrUpdate.
+	if !sets.NewString(instance.Status.ExcludeDates...).Equal(sets.NewString(instance.Spec.ExcludeDates...)) {
+		instance.Status.ExcludeDates = instance.Spec.ExcludeDates
+		noNeedUpdateStatus = false
+	}
+	if !apiequality.Semantic.DeepEqual(instance.Status.NamespaceDefaults, defaults) {
+		instance.Status.NamespaceDefaults = defaults
+		noNeedUpdateStatus = false
+	}
 
 	for _, job := range instance.Spec.Jobs {
 		jobCondition := v1beta1.Condition{
@@ -154,9 +290,11 @@ func (r *ReconcileCronHorizontalPodAutoscaler) Reconcile(context context.Context
 			Schedule:      job.Schedule,
 			RunOnce:       job.RunOnce,
 			TargetSize:    job.TargetSize,
+			RunCount:      runCounts[job.Name],
+			ReadyDuration: readyDurations[job.Name],
 			LastProbeTime: metav1.Time{Time: time.Now()},
 		}
-		j, err := CronHPAJobFactory(instance, job, r.CronManager.scaler, r.CronManager.mapper, r.Client)
+		j, err := CronHPAJobFactory(instance, defaults, job, r.CronManager.scaler, r.CronManager.mapper, r.CronManager.dynamicClient, r.CronManager.jobClient)
 
 		if err != nil {
 			jobCondition.State = v1beta1.Failed
@@ -164,10 +302,14 @@ func (r *ReconcileCronHorizontalPodAutoscaler) Reconcile(context context.Context
 				job.Name, instance.Name, instance.Namespace, err)
 			log.Errorf("Failed to create cron hpa job %s,because of %v", job.Name, err)
 		} else {
+			r.CronManager.applyJitter(j)
+			j.(*CronJobHPA).setReadyDuration(readyDurations[job.Name])
+			jobCondition.NormalizedSchedule = j.SchedulePlan()
+			jobCondition.JitterOffset = int32(j.(*CronJobHPA).jitter / time.Second)
 			name := job.Name
 			if c, ok := leftConditionsMap[name]; ok {
-				jobId := c.JobId
-				j.SetID(jobId)
+				// the id of the job is derived from its spec, so it is not read from the condition.
+				jobId := j.ID()
 
 				// run once and return when reaches the final state
 				if runOnce(job) && (c.State == v1beta1.Succeed || c.State == v1beta1.Failed) {
@@ -180,22 +322,69 @@ func (r *ReconcileCronHorizontalPodAutoscaler) Reconcile(context context.Context
 				}
 			}
 
+			// the job out of its active period is removed from the engine.
+			state, message, after := j.(*CronJobHPA).activeState(time.Now(), runCounts[name])
+			requeueAfter = minRequeueAfter(requeueAfter, after)
+			if state != "" {
+				c, ok := leftConditionsMap[name]
+				if ok && c.JobId != "" {
+					if err := r.CronManager.delete(c.JobId); err != nil {
+						log.Errorf("Failed to delete inactive job %s in cronHPA %s namespace %s,because of %v", name, instance.Name, instance.Namespace, err)
+					}
+				}
+				if ok && c.State == state && c.Message == message {
+					continue
+				}
+				jobCondition.State = state
+				jobCondition.Message = message
+				noNeedUpdateStatus = false
+				instance.Status.Conditions = updateConditions(instance.Status.Conditions, jobCondition)
+				continue
+			}
+
 			jobCondition.JobId = j.ID()
 			err := r.CronManager.createOrUpdate(j)
 			if err != nil {
 				if _, ok := err.(*NoNeedUpdate); ok {
-					continue
+					// the job is registered but the condition may be lost.
+					if _, found := leftConditionsMap[name]; found {
+						continue
+					}
+					jobCondition.State = v1beta1.Submitted
+					j.(*CronJobHPA).setFireTimes(&jobCondition, time.Now())
 				} else {
 					jobCondition.State = v1beta1.Failed
 					jobCondition.Message = fmt.Sprintf("Failed to update cron hpa job %s,because of %v", job.Name, err)
 				}
 			} else {
 				jobCondition.State = v1beta1.Submitted
+				j.(*CronJobHPA).setFireTimes(&jobCondition, time.Now())
 			}
 		}
 		noNeedUpdateStatus = false
 		instance.Status.Conditions = updateConditions(instance.Status.Conditions, jobCondition)
 	}
+	// the jobs which are not in the conditions, such as the old jobs of the changed specs whose conditions are lost.
+	keep := make(map[string]bool)
+	for _, c := range instance.Status.Conditions {
+		keep[c.JobId] = true
+	}
+	r.CronManager.deleteJobsExcept(request.NamespacedName, func(job *CronJobHPA) bool {
+		return job.profile == ""
+	}, keep)
+
+	// the profile is expanded into the jobs of its breakpoints, which share one status.
+	profileStatus := r.CronManager.syncProfile(instance, defaults)
+	if !apiequality.Semantic.DeepEqual(instance.Status.ProfileStatus, profileStatus) {
+		instance.Status.ProfileStatus = profileStatus
+		noNeedUpdateStatus = false
+	}
+	calendarConditions := iCalendarConditions(context, r.Client, instance, defaults)
+	if !apiequality.Semantic.DeepEqual(instance.Status.CalendarConditions, calendarConditions) {
+		instance.Status.CalendarConditions = calendarConditions
+		noNeedUpdateStatus = false
+	}
+
 	// conditions are not changed and no need to update.
 	if !noNeedUpdateStatus || len(leftConditions) != len(conditions) {
 		err := r.Update(context, instance)
@@ -205,7 +394,7 @@ func (r *ReconcileCronHorizontalPodAutoscaler) Reconcile(context context.Context
 	}
 
 	//log.Infof("%v has been handled completely.", instance)
-	return reconcile.Result{}, nil
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func convertConditionMaps(conditions []v1beta1.Condition) map[string]v1beta1.Condition {
@@ -227,16 +416,25 @@ func updateConditions(conditions []v1beta1.Condition, condition v1beta1.Conditio
 }
 
 // if global params changed then all jobs need to be recreated.
-func checkGlobalParamsChanges(status v1beta1.CronHorizontalPodAutoscalerStatus, spec v1beta1.CronHorizontalPodAutoscalerSpec) bool {
+func checkGlobalParamsChanges(status v1beta1.CronHorizontalPodAutoscalerStatus, spec v1beta1.CronHorizontalPodAutoscalerSpec, defaults *v1beta1.NamespaceDefaults) bool {
 	if &status.ScaleTargetRef != nil && (status.ScaleTargetRef.Kind != spec.ScaleTargetRef.Kind || status.ScaleTargetRef.ApiVersion != spec.ScaleTargetRef.ApiVersion ||
-		status.ScaleTargetRef.Name != spec.ScaleTargetRef.Name) {
+		status.ScaleTargetRef.Name != spec.ScaleTargetRef.Name || status.ScaleTargetRef.ReplicasPath != spec.ScaleTargetRef.ReplicasPath) {
 		return true
 	}
 
-	curExcludeDates := sets.NewString(spec.ExcludeDates...)
-	preExcludeDates := sets.NewString(status.ExcludeDates...)
-
-	return !curExcludeDates.Equal(preExcludeDates)
+	if !apiequality.Semantic.DeepEqual(status.ScaleTargetRefs, spec.ScaleTargetRefs) ||
+		!apiequality.Semantic.DeepEqual(status.ScaleTargetSelector, spec.ScaleTargetSelector) ||
+		!apiequality.Semantic.DeepEqual(status.Distribution, spec.Distribution) ||
+		!apiequality.Semantic.DeepEqual(status.Stages, spec.Stages) ||
+		!apiequality.Semantic.DeepEqual(status.ScaleDownOrder, spec.ScaleDownOrder) ||
+		!apiequality.Semantic.DeepEqual(status.ExcludeWindows, spec.ExcludeWindows) ||
+		!apiequality.Semantic.DeepEqual(status.ExcludeCalendars, spec.ExcludeCalendars) ||
+		!apiequality.Semantic.DeepEqual(status.IncludeCalendars, spec.IncludeCalendars) ||
+		!apiequality.Semantic.DeepEqual(withoutExcludeDates(status.NamespaceDefaults), withoutExcludeDates(defaults)) ||
+		status.Timezone != spec.Timezone {
+		return true
+	}
+	return false
 }
 
 func runOnce(job v1beta1.Job) bool {
@@ -6,24 +6,29 @@ import (
 	"fmt"
 	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
 	"github.com/ringtail/go-cron"
-	"github.com/satori/go.uuid"
 	autoscalingapi "k8s.io/api/autoscaling/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	scaleclient "k8s.io/client-go/scale"
 	log "k8s.io/klog/v2"
+	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const (
 	updateRetryInterval = 3 * time.Second
 	maxRetryTimeout     = 10 * time.Second
-	dateFormat          = "11-15-1990"
 )
 
 type CronJob interface {
@@ -32,7 +37,9 @@ type CronJob interface {
 	SetID(id string)
 	Equals(Job CronJob) bool
 	SchedulePlan() string
-	Ref() *TargetRef
+	Schedule() (cron.Schedule, error)
+	Refs() []*TargetRef
+	Selector() *TargetSelector
 	CronHPAMeta() *v1beta1.CronHorizontalPodAutoscaler
 	Run() (msg string, err error)
 }
@@ -43,25 +50,115 @@ type TargetRef struct {
 	RefKind      string
 	RefGroup     string
 	RefVersion   string
+	// ReplicasPath is the JSON pointer of the replicas in a workload without the scale subresource.
+	ReplicasPath string
 }
 
 // needed when compare equals.
 func (tr *TargetRef) toString() string {
-	return fmt.Sprintf("%s:%s:%s:%s:%s", tr.RefName, tr.RefNamespace, tr.RefKind, tr.RefGroup, tr.RefVersion)
+	return fmt.Sprintf("%s:%s:%s:%s:%s:%s", tr.RefName, tr.RefNamespace, tr.RefKind, tr.RefGroup, tr.RefVersion, tr.ReplicasPath)
+}
+
+// TargetSelector selects the targets of one kind in a namespace by labels.
+type TargetSelector struct {
+	RefNamespace string
+	RefKind      string
+	RefGroup     string
+	RefVersion   string
+	Selector     labels.Selector
+}
+
+// needed when compare equals.
+func (ts *TargetSelector) toString() string {
+	return fmt.Sprintf("%s:%s:%s:%s:%s", ts.RefNamespace, ts.RefKind, ts.RefGroup, ts.RefVersion, ts.Selector.String())
+}
+
+func targetsToString(refs []*TargetRef, selector *TargetSelector) string {
+	arr := make([]string, 0, len(refs)+1)
+	for _, ref := range refs {
+		arr = append(arr, ref.toString())
+	}
+	if selector != nil {
+		arr = append(arr, selector.toString())
+	}
+	return strings.Join(arr, ",")
+}
+
+// TargetResult is the outcome of one execution for one target.
+type TargetResult struct {
+	Ref         *TargetRef
+	DesiredSize int32
+	Stage       string
+	Skipped     bool
+	Msg         string
+	Err         error
 }
 
 type CronJobHPA struct {
-	TargetRef    *TargetRef
-	HPARef       *v1beta1.CronHorizontalPodAutoscaler
-	id           string
-	name         string
-	DesiredSize  int32
-	Plan         string
-	RunOnce      bool
-	scaler       scaleclient.ScalesGetter
-	mapper       apimeta.RESTMapper
-	excludeDates []string
-	client       client.Client
+	TargetRefs     []*TargetRef
+	TargetSelector *TargetSelector
+	HPARef         *v1beta1.CronHorizontalPodAutoscaler
+	id             string
+	name           string
+	DesiredSize    int32
+	Plan           string
+	// schedule as written in the spec, Plan is the normalized one.
+	schedule       string
+	RunOnce        bool
+	scaler         scaleclient.ScalesGetter
+	mapper         apimeta.RESTMapper
+	dynamicClient  dynamic.Interface
+	distribution   *v1beta1.DistributionPolicy
+	stages         []v1beta1.ScalingStage
+	scaleDownOrder []string
+	// excludeDates of the job, the cronHPA and the namespace.
+	excludeDates   []levelDates
+	includeDates   []string
+	excludeWindows []excludeWindow
+	// names of the ScalingCalendars of the cronHPA and the job.
+	excludeCalendars []string
+	includeCalendars []string
+	// iCalendar sources in the namespace of the cronHPA.
+	excludeICalendars []v1beta1.ICalendarRef
+	includeICalendars []v1beta1.ICalendarRef
+	// dates of the calendars, which are read when the job is built. A changed calendar
+	// triggers the reconcile which builds the job again and updates it in the engine.
+	excludeCalendarDates []calendarDates
+	includeCalendarDates []calendarDates
+	location             *time.Location
+	// the job is active from activeFrom until activeUntil, zero means no limit.
+	activeFrom  time.Time
+	activeUntil time.Time
+	maxRuns     int32
+	// jitterSeconds of the job, the default of the controller is used if it is nil.
+	jitterSeconds *int32
+	// jitter is the offset of the executions, which is set by the CronManager.
+	jitter   time.Duration
+	priority int32
+	// leadTime of the job and the readyDuration measured after the last scale-up for the adaptiveLeadTime.
+	leadTime         time.Duration
+	adaptiveLeadTime bool
+	readyDuration    time.Duration
+	disabled         bool
+	client           client.Client
+	// clusterPolicy is the name of the ClusterCronHorizontalPodAutoscaler
+	// which the job belongs to, HPARef is a view of it in one namespace.
+	clusterPolicy string
+	// profile is the breakpoint of the profile which the job is expanded from.
+	profile string
+
+	dstPolicy v1beta1.DSTPolicy
+
+	resultsLock sync.Mutex
+	results     []TargetResult
+	// the last adjustment of the dstPolicy, which is reported in the next result.
+	dstAdjustment string
+	// scaledAt is the time when the targets were scaled by the last execution.
+	scaledAt time.Time
+	// stopReadiness stops the measurement of the readiness after the last execution.
+	stopReadiness context.CancelFunc
+	// apiCalls counts the calls to the API server by the execution.
+	apiCalls int32
 }
 
 func (ch *CronJobHPA) SetID(id string) {
@@ -77,8 +174,20 @@ func (ch *CronJobHPA) ID() string {
 }
 
 func (ch *CronJobHPA) Equals(j CronJob) bool {
-	// update will create a new uuid
-	if ch.id == j.ID() && ch.SchedulePlan() == j.SchedulePlan() && ch.Ref().toString() == j.Ref().toString() {
+	// a changed job spec gets a new id
+	if ch.id == j.ID() && ch.SchedulePlan() == j.SchedulePlan() && targetsToString(ch.Refs(), ch.Selector()) == targetsToString(j.Refs(), j.Selector()) {
+		// the dates, calendars and iCalendars of a job are updated in place.
+		if job, ok := j.(*CronJobHPA); ok {
+			return ch.dstPolicy == job.dstPolicy && ch.offset() == job.offset() && ch.priority == job.priority &&
+				reflect.DeepEqual(ch.excludeDates, job.excludeDates) &&
+				strings.Join(ch.excludeCalendars, ",") == strings.Join(job.excludeCalendars, ",") &&
+				strings.Join(ch.includeCalendars, ",") == strings.Join(job.includeCalendars, ",") &&
+				strings.Join(ch.includeDates, ",") == strings.Join(job.includeDates, ",") &&
+				apiequality.Semantic.DeepEqual(ch.excludeICalendars, job.excludeICalendars) &&
+				apiequality.Semantic.DeepEqual(ch.includeICalendars, job.includeICalendars) &&
+				reflect.DeepEqual(ch.excludeCalendarDates, job.excludeCalendarDates) &&
+				reflect.DeepEqual(ch.includeCalendarDates, job.includeCalendarDates)
+		}
 		return true
 	}
 	return false
@@ -88,20 +197,235 @@ func (ch *CronJobHPA) SchedulePlan() string {
 	return ch.Plan
 }
 
-func (ch *CronJobHPA) Ref() *TargetRef {
-	return ch.TargetRef
+// now returns the current time in the timezone of the job.
+func (ch *CronJobHPA) now() time.Time {
+	if ch.location != nil {
+		return time.Now().In(ch.location)
+	}
+	return time.Now()
+}
+
+func (ch *CronJobHPA) Refs() []*TargetRef {
+	return ch.TargetRefs
+}
+
+func (ch *CronJobHPA) Selector() *TargetSelector {
+	return ch.TargetSelector
 }
 
 func (ch *CronJobHPA) CronHPAMeta() *v1beta1.CronHorizontalPodAutoscaler {
 	return ch.HPARef
 }
 
+// TargetResults returns the per target outcome of the last execution.
+func (ch *CronJobHPA) TargetResults() []TargetResult {
+	ch.resultsLock.Lock()
+	defer ch.resultsLock.Unlock()
+	return ch.results
+}
+
+func (ch *CronJobHPA) setTargetResults(results []TargetResult) {
+	ch.resultsLock.Lock()
+	defer ch.resultsLock.Unlock()
+	ch.results = results
+	ch.scaledAt = time.Now()
+}
+
+func (ch *CronJobHPA) setDSTAdjustment(adjustment string) {
+	ch.resultsLock.Lock()
+	defer ch.resultsLock.Unlock()
+	ch.dstAdjustment = adjustment
+}
+
+// DSTAdjustment returns the last adjustment of the dstPolicy.
+func (ch *CronJobHPA) DSTAdjustment() string {
+	ch.resultsLock.Lock()
+	defer ch.resultsLock.Unlock()
+	return ch.dstAdjustment
+}
+
 func (ch *CronJobHPA) Run() (msg string, err error) {
+	return ch.runWithPause(time.Sleep)
+}
+
+// runWithPause runs the job and calls pause for the retries and the polls of the readiness, so the
+// execution queue releases the worker of the job while it waits.
+func (ch *CronJobHPA) runWithPause(pause func(d time.Duration)) (msg string, err error) {
+
+	if ch.disabled {
+		return fmt.Sprintf("skip scaling activity,because cron scaling is disabled in namespace %s.", ch.HPARef.Namespace), nil
+	}
+
+	// the dates and the windows are checked at the time of the schedule, which is later than now with a lead time.
+	firedAt := ch.now()
+	now := ch.businessTime(firedAt)
+	// the engine may fire before the reconciler removes the job.
+	if !ch.isActiveAt(now) {
+		return fmt.Sprintf("skip scaling activity,because the job is not active at %s.", now.Format(time.RFC3339)), nil
+	}
 
-	if skip, msg := IsTodayOff(ch.excludeDates); skip {
+	// the dates in includeDates and includeCalendars are never excluded.
+	if !ch.isIncluded(now) {
+		if skip, msg := isTodayOffByLevels(ch.excludeDates, now); skip {
+			return msg, nil
+		}
+
+		if skip, msg := ch.isExcludedByCalendars(now); skip {
+			return msg, nil
+		}
+	}
+
+	if skip, msg := inExcludeWindow(ch.excludeWindows, ch.name, now); skip {
 		return msg, nil
 	}
 
+	atomic.StoreInt32(&ch.apiCalls, 0)
+	defer func() {
+		KubeAPICallsPerJobExecution.Observe(float64(atomic.LoadInt32(&ch.apiCalls)))
+	}()
+
+	refs, err := ch.resolveTargets()
+	if err != nil {
+		return "", err
+	}
+
+	sizes, err := ch.desiredSizes(refs)
+	if err != nil {
+		return "", fmt.Errorf("failed to distribute targetSize %d across %d targets of cronHPA job %s, because of %v", ch.DesiredSize, len(refs), ch.name, err)
+	}
+	ch.holdScaleDown(refs, sizes, firedAt, pause)
+
+	// the targets of a stage are scaled together.
+	results := ch.runStages(refs, sizes, pause)
+	ch.setTargetResults(results)
+
+	return summarizeTargetResults(results)
+}
+
+// resolveTargets returns the static targets and the targets matched by the selector right now.
+func (ch *CronJobHPA) resolveTargets() ([]*TargetRef, error) {
+	refs := make([]*TargetRef, 0, len(ch.TargetRefs))
+	seen := make(map[string]bool)
+	for _, ref := range ch.TargetRefs {
+		if !seen[ref.toString()] {
+			seen[ref.toString()] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	if ch.TargetSelector == nil {
+		return refs, nil
+	}
+
+	ts := ch.TargetSelector
+	mapping, err := ch.mapper.RESTMapping(schema.GroupKind{Group: ts.RefGroup, Kind: ts.RefKind}, ts.RefVersion)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create mapping for scaleTargetSelector,because of %v", err)
+	}
+	ch.countAPICall("list", mapping.Resource.Resource)
+	list, err := ch.dynamicClient.Resource(mapping.Resource).Namespace(ts.RefNamespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: ts.Selector.String(),
+	})
+	if err != nil {
+		if apierrors.IsForbidden(err) {
+			return nil, fmt.Errorf("failed to list %s in %s namespace by selector %s, because the controller is not allowed to list %s, grant the list verb of it to the role of the controller",
+				ts.RefKind, ts.RefNamespace, ts.Selector.String(), mapping.Resource.GroupResource())
+		}
+		return nil, fmt.Errorf("failed to list %s in %s namespace by selector %s, because of %v", ts.RefKind, ts.RefNamespace, ts.Selector.String(), err)
+	}
+	var cronHPAs []v1beta1.CronHorizontalPodAutoscaler
+	if ch.clusterPolicy != "" {
+		cronHPAList := &v1beta1.CronHorizontalPodAutoscalerList{}
+		if err := ch.client.List(context.Background(), cronHPAList, client.InNamespace(ts.RefNamespace)); err != nil {
+			return nil, fmt.Errorf("failed to list cronHPAs in %s namespace, because of %v", ts.RefNamespace, err)
+		}
+		cronHPAs = cronHPAList.Items
+	}
+	for _, item := range list.Items {
+		if isOverridden(cronHPAs, ts.RefKind, item.GetName(), item.GetLabels()) {
+			log.V(2).Infof("%s %s in %s namespace is scaled by a cronHPA and left out of cluster policy %s", ts.RefKind, item.GetName(), ts.RefNamespace, ch.clusterPolicy)
+			continue
+		}
+		ref := &TargetRef{
+			RefName:      item.GetName(),
+			RefNamespace: ts.RefNamespace,
+			RefKind:      ts.RefKind,
+			RefGroup:     ts.RefGroup,
+			RefVersion:   ts.RefVersion,
+		}
+		if !seen[ref.toString()] {
+			seen[ref.toString()] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// isOverridden tells whether a workload is scaled by one of the cronHPAs,
+// which overrides the cluster policies in the namespace.
+func isOverridden(cronHPAs []v1beta1.CronHorizontalPodAutoscaler, kind string, name string, itemLabels map[string]string) bool {
+	for _, cronHPA := range cronHPAs {
+		if cronHPA.Spec.ScaleTargetRef.Kind == kind && cronHPA.Spec.ScaleTargetRef.Name == name {
+			return true
+		}
+		for _, ref := range cronHPA.Spec.ScaleTargetRefs {
+			if ref.Kind == kind && ref.Name == name {
+				return true
+			}
+		}
+		if ts := cronHPA.Spec.ScaleTargetSelector; ts != nil && ts.Kind == kind && ts.Selector != nil {
+			selector, err := v1.LabelSelectorAsSelector(ts.Selector)
+			if err == nil && selector.Matches(labels.Set(itemLabels)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// desiredSizes returns the size of every target, which is the targetSize
+// of the job unless a distribution policy splits it across the targets.
+func (ch *CronJobHPA) desiredSizes(refs []*TargetRef) ([]int32, error) {
+	if ch.distribution != nil {
+		return distributeTargetSize(ch.DesiredSize, refs, ch.distribution)
+	}
+	sizes := make([]int32, len(refs))
+	for i := range refs {
+		sizes[i] = ch.DesiredSize
+	}
+	return sizes, nil
+}
+
+// summarizeTargetResults keeps the message of a single target as it is and merges the others.
+func summarizeTargetResults(results []TargetResult) (msg string, err error) {
+	switch len(results) {
+	case 0:
+		return "skip scaling activity,because no target is found.", nil
+	case 1:
+		return results[0].Msg, results[0].Err
+	}
+
+	failed := make([]string, 0)
+	skipped := 0
+	for _, r := range results {
+		if r.Err != nil {
+			if r.Stage != "" {
+				failed = append(failed, fmt.Sprintf("stage %s %s %s: %v", r.Stage, r.Ref.RefKind, r.Ref.RefName, r.Err))
+			} else {
+				failed = append(failed, fmt.Sprintf("%s %s: %v", r.Ref.RefKind, r.Ref.RefName, r.Err))
+			}
+		}
+		if r.Skipped {
+			skipped++
+		}
+	}
+	if len(failed) != 0 {
+		return "", fmt.Errorf("failed to scale %d of %d targets and skipped %d targets, because of [%s]", len(failed), len(results), skipped, strings.Join(failed, "; "))
+	}
+	return fmt.Sprintf("%d targets have been scaled.", len(results)), nil
+}
+
+func (ch *CronJobHPA) scaleTarget(ref *TargetRef, desiredSize int32, pause func(d time.Duration)) (msg string, err error) {
 	startTime := time.Now()
 	times := 0
 	for {
@@ -109,35 +433,39 @@ func (ch *CronJobHPA) Run() (msg string, err error) {
 
 		// timeout and exit
 		if startTime.Add(maxRetryTimeout).Before(now) {
-			return "", fmt.Errorf("failed to scale %s %s in %s namespace to %d after retrying %d times and exit,because of %v", ch.TargetRef.RefKind, ch.TargetRef.RefName, ch.TargetRef.RefNamespace, ch.DesiredSize, times, err)
+			return "", fmt.Errorf("failed to scale %s %s in %s namespace to %d after retrying %d times and exit,because of %v", ref.RefKind, ref.RefName, ref.RefNamespace, desiredSize, times, err)
 		}
 
 		// hpa compatible
-		if ch.TargetRef.RefKind == "HorizontalPodAutoscaler" {
-			msg, err = ch.ScaleHPA()
+		if ref.RefKind == "HorizontalPodAutoscaler" {
+			msg, err = ch.ScaleHPA(ref, desiredSize)
+			if err == nil {
+				break
+			}
+		} else if ref.ReplicasPath != "" {
+			msg, err = ch.ScalePathRef(ref, desiredSize)
 			if err == nil {
 				break
 			}
 		} else {
-			msg, err = ch.ScalePlainRef()
+			msg, err = ch.ScalePlainRef(ref, desiredSize)
 			if err == nil {
 				break
 			}
 		}
-		time.Sleep(updateRetryInterval)
+		pause(updateRetryInterval)
 		times = times + 1
 	}
 
 	return msg, err
 }
 
-func (ch *CronJobHPA) ScaleHPA() (msg string, err error) {
-	var scale *autoscalingapi.Scale
-	var targetGR schema.GroupResource
-
+// ScaleHPA changes the bounds of the HPA to the desired size and scales its target when it runs fewer replicas.
+// The HPA is read from the informer.
+func (ch *CronJobHPA) ScaleHPA(ref *TargetRef, desiredSize int32) (msg string, err error) {
 	ctx := context.Background()
 	hpa := &autoscalingapi.HorizontalPodAutoscaler{}
-	err = ch.client.Get(ctx, types.NamespacedName{Namespace: ch.HPARef.Namespace, Name: ch.TargetRef.RefName}, hpa)
+	err = ch.client.Get(ctx, types.NamespacedName{Namespace: ref.RefNamespace, Name: ref.RefName}, hpa)
 
 	if err != nil {
 		return "", fmt.Errorf("Failed to get HorizontalPodAutoscaler Ref,because of %v", err)
@@ -155,171 +483,325 @@ func (ch *CronJobHPA) ScaleHPA() (msg string, err error) {
 		Group: targetGV.Group,
 	}
 
-	mappings, err := ch.mapper.RESTMappings(targetGK)
-	if err != nil {
-		return "", fmt.Errorf("Failed to create mapping,because of %v", err)
-	}
-
-	found := false
-	for _, mapping := range mappings {
-		targetGR = mapping.Resource.GroupResource()
-		scale, err = ch.scaler.Scales(ch.TargetRef.RefNamespace).Get(context.Background(), targetGR, targetRef.Name, v1.GetOptions{})
-		if err == nil {
-			found = true
-			break
-		}
-	}
-
-	if found == false {
-		log.Errorf("failed to found source target %s %s in %s namespace", ch.TargetRef.RefKind, ch.TargetRef.RefName, ch.TargetRef.RefNamespace)
-		return "", fmt.Errorf("failed to found source target %s %s in %s namespace, err is %v", ch.TargetRef.RefKind, ch.TargetRef.RefName, ch.TargetRef.RefNamespace, err)
-	}
-
 	updateHPA := false
 
-	if ch.DesiredSize > hpa.Spec.MaxReplicas {
-		hpa.Spec.MaxReplicas = ch.DesiredSize
+	if desiredSize > hpa.Spec.MaxReplicas {
+		hpa.Spec.MaxReplicas = desiredSize
 		updateHPA = true
 	}
 
-	if ch.DesiredSize < *hpa.Spec.MinReplicas {
-		*hpa.Spec.MinReplicas = ch.DesiredSize
+	if desiredSize < *hpa.Spec.MinReplicas {
+		*hpa.Spec.MinReplicas = desiredSize
 		updateHPA = true
 	}
 
 	//
-	if hpa.Status.CurrentReplicas == *hpa.Spec.MinReplicas && ch.DesiredSize < hpa.Status.CurrentReplicas {
-		*hpa.Spec.MinReplicas = ch.DesiredSize
+	if hpa.Status.CurrentReplicas == *hpa.Spec.MinReplicas && desiredSize < hpa.Status.CurrentReplicas {
+		*hpa.Spec.MinReplicas = desiredSize
 		updateHPA = true
 	}
 
-	if hpa.Status.CurrentReplicas < ch.DesiredSize {
-		*hpa.Spec.MinReplicas = ch.DesiredSize
+	if hpa.Status.CurrentReplicas < desiredSize {
+		*hpa.Spec.MinReplicas = desiredSize
 		updateHPA = true
 	}
 
 	if updateHPA {
+		ch.countAPICall("update", "horizontalpodautoscalers")
 		err = ch.client.Update(ctx, hpa)
 		if err != nil {
 			return "", err
 		}
 	}
 
-	if hpa.Status.CurrentReplicas >= ch.DesiredSize {
+	if hpa.Status.CurrentReplicas >= desiredSize {
 		// skip change replicas and exit
 		return fmt.Sprintf("Skip scale replicas because HPA %s in namespace %s current replicas:%d >= desired replicas:%d.",
-			hpa.Name, hpa.Namespace, scale.Spec.Replicas, ch.DesiredSize), nil
+			hpa.Name, hpa.Namespace, hpa.Status.CurrentReplicas, desiredSize), nil
 	}
 
-	msg = fmt.Sprintf("current replicas:%d, desired replicas:%d.", scale.Spec.Replicas, ch.DesiredSize)
+	msg = fmt.Sprintf("current replicas:%d, desired replicas:%d.", hpa.Status.CurrentReplicas, desiredSize)
 
-	scale.Spec.Replicas = int32(ch.DesiredSize)
-	_, err = ch.scaler.Scales(ch.TargetRef.RefNamespace).Update(context.Background(), targetGR, scale, metav1.UpdateOptions{})
-	if err != nil {
-		return "", fmt.Errorf("failed to scale %s %s in %s namespace to %d, because of %v", ch.TargetRef.RefKind, ch.TargetRef.RefName, ch.TargetRef.RefNamespace, ch.DesiredSize, err)
+	if _, err = ch.patchScale(ref.RefNamespace, targetGK, targetRef.Name, desiredSize); err != nil {
+		return "", fmt.Errorf("failed to scale %s %s in %s namespace to %d, because of %w", ref.RefKind, ref.RefName, ref.RefNamespace, desiredSize, err)
 	}
 	return msg, nil
 }
 
-func (ch *CronJobHPA) ScalePlainRef() (msg string, err error) {
-	var scale *autoscalingapi.Scale
-	var targetGR schema.GroupResource
-
+func (ch *CronJobHPA) ScalePlainRef(ref *TargetRef, desiredSize int32) (msg string, err error) {
 	targetGK := schema.GroupKind{
-		Group: ch.TargetRef.RefGroup,
-		Kind:  ch.TargetRef.RefKind,
+		Group: ref.RefGroup,
+		Kind:  ref.RefKind,
 	}
-	mappings, err := ch.mapper.RESTMappings(targetGK)
+	scale, err := ch.patchScale(ref.RefNamespace, targetGK, ref.RefName, desiredSize)
 	if err != nil {
-		return "", fmt.Errorf("Failed to create create mapping,because of %v", err)
+		log.Errorf("failed to scale %s %s in %s namespace to %d, because of %v", ref.RefKind, ref.RefName, ref.RefNamespace, desiredSize, err)
+		return "", fmt.Errorf("failed to scale %s %s in %s namespace to %d, because of %w", ref.RefKind, ref.RefName, ref.RefNamespace, desiredSize, err)
 	}
+	log.Infof("%s %s in namespace %s has been scaled successfully. job: %s replicas: %d id: %s", ref.RefKind, ref.RefName, ref.RefNamespace, ch.Name(), desiredSize, ch.ID())
 
-	found := false
+	// the status of the scale is not changed by the patch yet.
+	return fmt.Sprintf("current replicas:%d, desired replicas:%d.", scale.Status.Replicas, desiredSize), nil
+}
+
+// patchScale sets the replicas with a merge patch of the scale subresource, so the scale is not read before.
+// The mappings of the kind are tried in order and the scale returned by the patch is returned.
+func (ch *CronJobHPA) patchScale(namespace string, targetGK schema.GroupKind, name string, desiredSize int32) (*autoscalingapi.Scale, error) {
+	mappings, err := ch.mapper.RESTMappings(targetGK)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create mapping,because of %v", err)
+	}
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, desiredSize))
 	for _, mapping := range mappings {
-		targetGR = mapping.Resource.GroupResource()
-		scale, err = ch.scaler.Scales(ch.TargetRef.RefNamespace).Get(context.Background(), targetGR, ch.TargetRef.RefName, v1.GetOptions{})
-		if err == nil {
-			found = true
-			log.Infof("%s %s in namespace %s has been scaled successfully. job: %s replicas: %d id: %s", ch.TargetRef.RefKind, ch.TargetRef.RefName, ch.TargetRef.RefNamespace, ch.Name(), ch.DesiredSize, ch.ID())
-			break
+		ch.countAPICall("patch", "scale")
+		scale, patchErr := ch.scaler.Scales(namespace).Patch(context.Background(), mapping.Resource, name, types.MergePatchType, patch, metav1.PatchOptions{})
+		if patchErr == nil {
+			return scale, nil
 		}
+		err = patchErr
 	}
-
-	if found == false {
-		log.Errorf("failed to find source target %s %s in %s namespace", ch.TargetRef.RefKind, ch.TargetRef.RefName, ch.TargetRef.RefNamespace)
-		return "", fmt.Errorf("failed to find source target %s %s in %s namespace", ch.TargetRef.RefKind, ch.TargetRef.RefName, ch.TargetRef.RefNamespace)
+	if err == nil {
+		err = fmt.Errorf("no mapping found for %s", targetGK)
 	}
+	return nil, err
+}
 
-	msg = fmt.Sprintf("current replicas:%d, desired replicas:%d.", scale.Spec.Replicas, ch.DesiredSize)
-
-	scale.Spec.Replicas = int32(ch.DesiredSize)
-	_, err = ch.scaler.Scales(ch.TargetRef.RefNamespace).Update(context.Background(), targetGR, scale, metav1.UpdateOptions{})
-	if err != nil {
-		return "", fmt.Errorf("failed to scale %s %s in %s namespace to %d, because of %v", ch.TargetRef.RefKind, ch.TargetRef.RefName, ch.TargetRef.RefNamespace, ch.DesiredSize, err)
-	}
-	return msg, nil
+// countAPICall counts a call to the API server in the metrics and in the calls of the execution.
+func (ch *CronJobHPA) countAPICall(verb string, resource string) {
+	atomic.AddInt32(&ch.apiCalls, 1)
+	countAPICall(verb, resource)
 }
 
+// checkRefValid checks the properties of the ref, the group is empty for the core group.
 func checkRefValid(ref *TargetRef) error {
-	if ref.RefVersion == "" || ref.RefGroup == "" || ref.RefName == "" || ref.RefNamespace == "" || ref.RefKind == "" {
+	if ref.RefVersion == "" || ref.RefName == "" || ref.RefNamespace == "" || ref.RefKind == "" {
 		return errors.New("any properties in ref could not be empty")
 	}
 	return nil
 }
 
-func checkPlanValid(plan string) error {
+// parseApiVersion parses the apiVersion of a target, such as apps/v1, or v1 for the core group.
+func parseApiVersion(apiVersion string) (schema.GroupVersion, error) {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return gv, fmt.Errorf("invalid apiVersion %s, because of %v", apiVersion, err)
+	}
+	if gv.Version == "" {
+		return gv, fmt.Errorf("invalid apiVersion %s, because the version is empty", apiVersion)
+	}
+	return gv, nil
+}
+
+// checkPlanValid parses the plan with the same parser which schedules the job,
+// and rejects the plan which never fires after now, such as a plan in a past year.
+func checkPlanValid(plan string, now time.Time) error {
+	schedule, err := ParseSchedule(plan)
+	if err != nil {
+		return fmt.Errorf("invalid schedule %s, because of %v", plan, err)
+	}
+	if schedule.Next(now).IsZero() {
+		return fmt.Errorf("invalid schedule %s, because it never fires after %s", plan, now.Format(time.RFC3339))
+	}
 	return nil
 }
 
-func CronHPAJobFactory(instance *v1beta1.CronHorizontalPodAutoscaler, job v1beta1.Job, scaler scaleclient.ScalesGetter, mapper apimeta.RESTMapper, client client.Client) (CronJob, error) {
-	arr := strings.Split(instance.Spec.ScaleTargetRef.ApiVersion, "/")
-	group := arr[0]
-	version := arr[1]
+func newTargetRef(namespace string, scaleTargetRef v1beta1.ScaleTargetRef) (*TargetRef, error) {
+	gv, err := parseApiVersion(scaleTargetRef.ApiVersion)
+	if err != nil {
+		return nil, err
+	}
+	replicasPath, err := parseReplicasPath(scaleTargetRef.ReplicasPath)
+	if err != nil {
+		return nil, err
+	}
 	ref := &TargetRef{
-		RefName:      instance.Spec.ScaleTargetRef.Name,
-		RefKind:      instance.Spec.ScaleTargetRef.Kind,
-		RefNamespace: instance.Namespace,
-		RefGroup:     group,
-		RefVersion:   version,
+		RefName:      scaleTargetRef.Name,
+		RefKind:      scaleTargetRef.Kind,
+		RefNamespace: namespace,
+		RefGroup:     gv.Group,
+		RefVersion:   gv.Version,
+		ReplicasPath: replicasPath,
 	}
 
 	if err := checkRefValid(ref); err != nil {
 		return nil, err
 	}
-	if err := checkPlanValid(job.Schedule); err != nil {
-		return nil, err
+	return ref, nil
+}
+
+func newTargetSelector(namespace string, scaleTargetSelector *v1beta1.ScaleTargetSelector) (*TargetSelector, error) {
+	if scaleTargetSelector.Kind == "" || scaleTargetSelector.Selector == nil {
+		return nil, errors.New("kind and selector in scaleTargetSelector could not be empty")
 	}
-	return &CronJobHPA{
-		id:           uuid.Must(uuid.NewV4(), nil).String(),
-		TargetRef:    ref,
-		HPARef:       instance,
-		name:         job.Name,
-		Plan:         job.Schedule,
-		DesiredSize:  job.TargetSize,
-		RunOnce:      job.RunOnce,
-		scaler:       scaler,
-		mapper:       mapper,
-		excludeDates: instance.Spec.ExcludeDates,
-		client:       client,
+	gv, err := parseApiVersion(scaleTargetSelector.ApiVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scaleTargetSelector, because of %v", err)
+	}
+	selector, err := v1.LabelSelectorAsSelector(scaleTargetSelector.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector in scaleTargetSelector, because of %v", err)
+	}
+	return &TargetSelector{
+		RefNamespace: namespace,
+		RefKind:      scaleTargetSelector.Kind,
+		RefGroup:     gv.Group,
+		RefVersion:   gv.Version,
+		Selector:     selector,
 	}, nil
 }
 
-func IsTodayOff(excludeDates []string) (bool, string) {
-
-	if excludeDates == nil {
-		return false, ""
+func CronHPAJobFactory(instance *v1beta1.CronHorizontalPodAutoscaler, defaults *v1beta1.NamespaceDefaults, job v1beta1.Job, scaler scaleclient.ScalesGetter, mapper apimeta.RESTMapper, dynamicClient dynamic.Interface, client client.Client) (CronJob, error) {
+	refs := make([]*TargetRef, 0, len(instance.Spec.ScaleTargetRefs)+1)
+	// scaleTargetRef is optional when scaleTargetRefs or scaleTargetSelector is used.
+	if instance.Spec.ScaleTargetRef != (v1beta1.ScaleTargetRef{}) {
+		ref, err := newTargetRef(instance.Namespace, instance.Spec.ScaleTargetRef)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	for _, scaleTargetRef := range instance.Spec.ScaleTargetRefs {
+		ref, err := newTargetRef(instance.Namespace, scaleTargetRef)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
 	}
 
-	now := time.Now()
-	for _, date := range excludeDates {
-		schedule, err := cron.Parse(date)
+	var selector *TargetSelector
+	if instance.Spec.ScaleTargetSelector != nil {
+		var err error
+		selector, err = newTargetSelector(instance.Namespace, instance.Spec.ScaleTargetSelector)
 		if err != nil {
-			log.Warningf("Failed to parse schedule %s,and skip this date,because of %v", date, err)
-			continue
+			return nil, err
 		}
-		if nextTime := schedule.Next(now); nextTime.Format(dateFormat) == now.Format(dateFormat) {
-			return true, fmt.Sprintf("skip scaling activity,because of excludeDate (%s).", date)
+	}
+
+	if len(refs) == 0 && selector == nil {
+		return nil, errors.New("one of scaleTargetRef, scaleTargetRefs and scaleTargetSelector is required")
+	}
+	format := job.ScheduleFormat
+	if format == "" {
+		format = instance.Spec.ScheduleFormat
+	}
+	plan, err := normalizeSchedule(job.Schedule, format)
+	if err != nil {
+		return nil, err
+	}
+	location, err := loadTimezone(instance.Spec.Timezone, defaults)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone, because of %v", err)
+	}
+	now := time.Now()
+	if location != nil {
+		now = now.In(location)
+	}
+	if err := checkPlanValid(plan, now); err != nil {
+		return nil, err
+	}
+	excludeWindows, err := newExcludeWindows(instance.Spec.ExcludeWindows, location)
+	if err != nil {
+		return nil, err
+	}
+	activeFrom, err := parseActiveTime(job.ActiveFrom, location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid activeFrom, because of %v", err)
+	}
+	activeUntil, err := parseActiveTime(job.ActiveUntil, location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid activeUntil, because of %v", err)
+	}
+	if !activeFrom.IsZero() && !activeUntil.IsZero() && !activeUntil.After(activeFrom) {
+		return nil, errors.New("activeUntil is not after activeFrom")
+	}
+	if job.MaxRuns < 0 {
+		return nil, errors.New("maxRuns could not be negative")
+	}
+	if job.JitterSeconds != nil && *job.JitterSeconds < 0 {
+		return nil, errors.New("jitterSeconds could not be negative")
+	}
+	leadTime, err := parseLeadTime(job.LeadTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid leadTime, because of %v", err)
+	}
+	excludeDates := []levelDates{{level: jobLevel, dates: job.ExcludeDates}, {level: cronHPALevel, dates: instance.Spec.ExcludeDates}}
+	disabled := false
+	if defaults != nil {
+		excludeDates = append(excludeDates, levelDates{level: namespaceLevel, dates: defaults.ExcludeDates})
+		disabled = defaults.Disabled
+	}
+	ch := &CronJobHPA{
+		id:                jobID(instance, defaults, job),
+		TargetRefs:        refs,
+		TargetSelector:    selector,
+		HPARef:            instance,
+		name:              job.Name,
+		Plan:              plan,
+		schedule:          job.Schedule,
+		DesiredSize:       job.TargetSize,
+		RunOnce:           job.RunOnce,
+		scaler:            scaler,
+		mapper:            mapper,
+		dynamicClient:     dynamicClient,
+		distribution:      instance.Spec.Distribution,
+		stages:            instance.Spec.Stages,
+		scaleDownOrder:    instance.Spec.ScaleDownOrder,
+		excludeDates:      excludeDates,
+		includeDates:      mergeUnique(instance.Spec.IncludeDates, job.IncludeDates),
+		excludeWindows:    excludeWindows,
+		excludeCalendars:  mergeUnique(instance.Spec.ExcludeCalendars, job.ExcludeCalendars),
+		includeCalendars:  mergeUnique(instance.Spec.IncludeCalendars, job.IncludeCalendars),
+		excludeICalendars: instance.Spec.ExcludeICalendars,
+		includeICalendars: instance.Spec.IncludeICalendars,
+		location:          location,
+		activeFrom:        activeFrom,
+		activeUntil:       activeUntil,
+		maxRuns:           job.MaxRuns,
+		jitterSeconds:     job.JitterSeconds,
+		priority:          job.Priority,
+		leadTime:          leadTime,
+		adaptiveLeadTime:  job.AdaptiveLeadTime,
+		dstPolicy:         instance.Spec.DSTPolicy,
+		disabled:          disabled,
+		client:            client,
+	}
+	ch.excludeCalendarDates = ch.loadCalendars(ch.excludeCalendars, ch.excludeICalendars)
+	ch.includeCalendarDates = ch.loadCalendars(ch.includeCalendars, ch.includeICalendars)
+	return ch, nil
+}
+
+// ClusterCronHPAJobFactory creates the job of a cluster policy in one namespace.
+// The job runs the same way as the job of a cronHPA which selects the workloads in the namespace.
+func ClusterCronHPAJobFactory(cluster *v1beta1.ClusterCronHorizontalPodAutoscaler, namespace string, defaults *v1beta1.NamespaceDefaults, job v1beta1.Job, scaler scaleclient.ScalesGetter, mapper apimeta.RESTMapper, dynamicClient dynamic.Interface, client client.Client) (CronJob, error) {
+	instance := &v1beta1.CronHorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cluster.Name,
+			Namespace: namespace,
+			UID:       cluster.UID,
+		},
+		Spec: v1beta1.CronHorizontalPodAutoscalerSpec{
+			ScaleTargetSelector: cluster.Spec.ScaleTargetSelector.DeepCopy(),
+			ExcludeDates:        cluster.Spec.ExcludeDates,
+			IncludeDates:        cluster.Spec.IncludeDates,
+			ExcludeWindows:      cluster.Spec.ExcludeWindows,
+			ExcludeCalendars:    cluster.Spec.ExcludeCalendars,
+			IncludeCalendars:    cluster.Spec.IncludeCalendars,
+			ScheduleFormat:      cluster.Spec.ScheduleFormat,
+			DSTPolicy:           cluster.Spec.DSTPolicy,
+			Timezone:            cluster.Spec.Timezone,
+			Jobs:                cluster.Spec.Jobs,
+		},
+	}
+	j, err := CronHPAJobFactory(instance, defaults, job, scaler, mapper, dynamicClient, client)
+	if err != nil {
+		return nil, err
+	}
+	ch := j.(*CronJobHPA)
+	ch.clusterPolicy = cluster.Name
+	for i := range ch.excludeDates {
+		if ch.excludeDates[i].level == cronHPALevel {
+			ch.excludeDates[i].level = clusterCronHPALevel
 		}
 	}
-	return false, ""
+	return ch, nil
 }
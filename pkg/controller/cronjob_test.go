@@ -0,0 +1,240 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	autoscalingapi "k8s.io/api/autoscaling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	fakescale "k8s.io/client-go/scale/fake"
+	core "k8s.io/client-go/testing"
+	"strings"
+	"testing"
+)
+
+func TestParseApiVersion(t *testing.T) {
+	testCases := []struct {
+		apiVersion string
+		gv         schema.GroupVersion
+		wantErr    bool
+	}{
+		{apiVersion: "v1", gv: schema.GroupVersion{Version: "v1"}},
+		{apiVersion: "apps/v1", gv: schema.GroupVersion{Group: "apps", Version: "v1"}},
+		{apiVersion: "autoscaling.alibabacloud.com/v1beta1", gv: schema.GroupVersion{Group: "autoscaling.alibabacloud.com", Version: "v1beta1"}},
+		{apiVersion: "", wantErr: true},
+		{apiVersion: "a/b/c", wantErr: true},
+		{apiVersion: "apps/", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.apiVersion, func(t *testing.T) {
+			gv, err := parseApiVersion(tc.apiVersion)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseApiVersion(%q) error = %v, wantErr %v", tc.apiVersion, err, tc.wantErr)
+			}
+			if !tc.wantErr && gv != tc.gv {
+				t.Errorf("parseApiVersion(%q) = %v, want %v", tc.apiVersion, gv, tc.gv)
+			}
+		})
+	}
+}
+
+func TestNewTargetRef(t *testing.T) {
+	testCases := []struct {
+		name    string
+		ref     v1beta1.ScaleTargetRef
+		want    *TargetRef
+		wantErr bool
+	}{
+		{
+			name: "core group",
+			ref:  v1beta1.ScaleTargetRef{ApiVersion: "v1", Kind: "ReplicationController", Name: "rc"},
+			want: &TargetRef{RefName: "rc", RefNamespace: "default", RefKind: "ReplicationController", RefVersion: "v1"},
+		},
+		{
+			name: "grouped",
+			ref:  v1beta1.ScaleTargetRef{ApiVersion: "apps/v1", Kind: "Deployment", Name: "nginx"},
+			want: &TargetRef{RefName: "nginx", RefNamespace: "default", RefKind: "Deployment", RefGroup: "apps", RefVersion: "v1"},
+		},
+		{
+			name: "crd",
+			ref:  v1beta1.ScaleTargetRef{ApiVersion: "autoscaling.alibabacloud.com/v1beta1", Kind: "ElasticWorkload", Name: "ew"},
+			want: &TargetRef{RefName: "ew", RefNamespace: "default", RefKind: "ElasticWorkload", RefGroup: "autoscaling.alibabacloud.com", RefVersion: "v1beta1"},
+		},
+		{
+			name: "replicasPath",
+			ref:  v1beta1.ScaleTargetRef{ApiVersion: "example.com/v1", Kind: "WorkerPool", Name: "pool", ReplicasPath: ".spec.workers[0].replicas"},
+			want: &TargetRef{RefName: "pool", RefNamespace: "default", RefKind: "WorkerPool", RefGroup: "example.com", RefVersion: "v1", ReplicasPath: "/spec/workers/0/replicas"},
+		},
+		{
+			name:    "empty apiVersion",
+			ref:     v1beta1.ScaleTargetRef{Kind: "Deployment", Name: "nginx"},
+			wantErr: true,
+		},
+		{
+			name:    "too many parts",
+			ref:     v1beta1.ScaleTargetRef{ApiVersion: "a/b/c", Kind: "Deployment", Name: "nginx"},
+			wantErr: true,
+		},
+		{
+			name:    "empty name",
+			ref:     v1beta1.ScaleTargetRef{ApiVersion: "apps/v1", Kind: "Deployment"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ref, err := newTargetRef("default", tc.ref)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("newTargetRef(%v) error = %v, wantErr %v", tc.ref, err, tc.wantErr)
+			}
+			if !tc.wantErr && *ref != *tc.want {
+				t.Errorf("newTargetRef(%v) = %+v, want %+v", tc.ref, *ref, *tc.want)
+			}
+		})
+	}
+}
+
+// newTestMapper maps a core, a grouped and a CRD kind.
+func newTestMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{
+		{Version: "v1"},
+		{Group: "apps", Version: "v1"},
+		{Group: "autoscaling.alibabacloud.com", Version: "v1beta1"},
+	})
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ReplicationController"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "autoscaling.alibabacloud.com", Version: "v1beta1", Kind: "ElasticWorkload"}, meta.RESTScopeNamespace)
+	return mapper
+}
+
+func TestScalePlainRef(t *testing.T) {
+	testCases := []struct {
+		name string
+		ref  *TargetRef
+		gvr  schema.GroupVersionResource
+	}{
+		{
+			name: "core group",
+			ref:  &TargetRef{RefName: "rc", RefNamespace: "default", RefKind: "ReplicationController", RefVersion: "v1"},
+			gvr:  schema.GroupVersionResource{Version: "v1", Resource: "replicationcontrollers"},
+		},
+		{
+			name: "grouped",
+			ref:  &TargetRef{RefName: "nginx", RefNamespace: "default", RefKind: "Deployment", RefGroup: "apps", RefVersion: "v1"},
+			gvr:  schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+		},
+		{
+			name: "crd",
+			ref:  &TargetRef{RefName: "ew", RefNamespace: "default", RefKind: "ElasticWorkload", RefGroup: "autoscaling.alibabacloud.com", RefVersion: "v1beta1"},
+			gvr:  schema.GroupVersionResource{Group: "autoscaling.alibabacloud.com", Version: "v1beta1", Resource: "elasticworkloads"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scaler := &fakescale.FakeScaleClient{}
+			var patched schema.GroupVersionResource
+			var patch string
+			scaler.AddReactor("patch", "*", func(action core.Action) (bool, runtime.Object, error) {
+				patchAction := action.(core.PatchAction)
+				patched = patchAction.GetResource()
+				patch = string(patchAction.GetPatch())
+				if patchAction.GetSubresource() != "scale" {
+					return true, nil, fmt.Errorf("unexpected subresource %s", patchAction.GetSubresource())
+				}
+				return true, &autoscalingapi.Scale{
+					ObjectMeta: metav1.ObjectMeta{Name: patchAction.GetName(), Namespace: patchAction.GetNamespace()},
+					Spec:       autoscalingapi.ScaleSpec{Replicas: 5},
+					Status:     autoscalingapi.ScaleStatus{Replicas: 2},
+				}, nil
+			})
+
+			job := &CronJobHPA{name: "scale-up", scaler: scaler, mapper: newTestMapper()}
+			msg, err := job.ScalePlainRef(tc.ref, 5)
+			if err != nil {
+				t.Fatalf("ScalePlainRef() error = %v", err)
+			}
+			if patched != tc.gvr {
+				t.Errorf("patched %v, want %v", patched, tc.gvr)
+			}
+			if patch != `{"spec":{"replicas":5}}` {
+				t.Errorf("patch = %s", patch)
+			}
+			if msg != "current replicas:2, desired replicas:5." {
+				t.Errorf("ScalePlainRef() = %q", msg)
+			}
+		})
+	}
+}
+
+func TestPatchScaleUnknownKind(t *testing.T) {
+	job := &CronJobHPA{name: "scale-up", scaler: &fakescale.FakeScaleClient{}, mapper: newTestMapper()}
+	_, err := job.patchScale("default", schema.GroupKind{Kind: "Deployment"}, "nginx", 3)
+	if err == nil || !strings.Contains(err.Error(), "Failed to create mapping") {
+		t.Errorf("patchScale() error = %v, want a mapping error", err)
+	}
+}
+
+func TestEqualsComparesExcludeDates(t *testing.T) {
+	newJob := func(dates ...string) *CronJobHPA {
+		return &CronJobHPA{
+			id:           "id",
+			Plan:         "0 0 8 * * *",
+			TargetRefs:   []*TargetRef{{RefName: "nginx", RefNamespace: "default", RefKind: "Deployment", RefGroup: "apps", RefVersion: "v1"}},
+			excludeDates: []levelDates{{level: jobLevel, dates: dates}, {level: cronHPALevel}},
+		}
+	}
+	if !newJob("2026-10-01").Equals(newJob("2026-10-01")) {
+		t.Errorf("Equals() of the same excludeDates = false")
+	}
+	if newJob("2026-10-01").Equals(newJob("2026-10-02")) {
+		t.Errorf("Equals() of different excludeDates = true")
+	}
+}
+
+// forbiddenDynamic rejects the lists like a role without the list verb.
+type forbiddenDynamic struct {
+	listDynamic
+}
+
+func (d *forbiddenDynamic) Resource(resource schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &forbiddenResource{gr: resource.GroupResource()}
+}
+
+type forbiddenResource struct {
+	listResource
+	gr schema.GroupResource
+}
+
+func (r *forbiddenResource) Namespace(namespace string) dynamic.ResourceInterface { return r }
+
+func (r *forbiddenResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	return nil, apierrors.NewForbidden(r.gr, "", fmt.Errorf("cannot list resource %q", r.gr.Resource))
+}
+
+func TestResolveTargetsForbidden(t *testing.T) {
+	job := &CronJobHPA{
+		name:          "scale-up",
+		mapper:        newTestMapper(),
+		dynamicClient: &forbiddenDynamic{},
+		TargetSelector: &TargetSelector{
+			RefNamespace: "default",
+			RefKind:      "ReplicationController",
+			RefVersion:   "v1",
+			Selector:     labels.SelectorFromSet(labels.Set{"app": "nginx"}),
+		},
+	}
+	_, err := job.resolveTargets()
+	if err == nil || !strings.Contains(err.Error(), "the controller is not allowed to list replicationcontrollers") {
+		t.Errorf("resolveTargets() error = %v, want the missing permission", err)
+	}
+}
@@ -7,18 +7,19 @@ import (
 	scalelib "github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/lib"
 	"github.com/ringtail/go-cron"
 	"k8s.io/api/core/v1"
-	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/scale"
 	"k8s.io/client-go/tools/record"
 	log "k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
 	"sync"
 	"time"
 )
@@ -34,41 +35,70 @@ func (n NoNeedUpdate) Error() string {
 	return "NoNeedUpdate"
 }
 
+// CronManagerOptions are the controller-wide settings of the jobs.
+type CronManagerOptions struct {
+	// DefaultJitterSeconds is the jitterSeconds of the jobs which don't set it.
+	DefaultJitterSeconds int32
+	// Workers is the number of the jobs which run at the same time, 0 means no limit.
+	Workers int
+	// ScaleQPS and ScaleBurst limit the calls of the jobs to the API server, 0 means no limit.
+	ScaleQPS   float32
+	ScaleBurst int
+	// StatusBatchWindow is the time in which the results of the jobs of an object are written together, 0 writes them at once.
+	StatusBatchWindow time.Duration
+}
+
 type CronManager struct {
 	sync.Mutex
-	cfg      *rest.Config
-	client   client.Client
-	jobQueue *sync.Map
+	options CronManagerOptions
+	cfg     *rest.Config
+	client  client.Client
+	// jobClient is the client of the jobs whose writes are limited with the scale subresource.
+	jobClient client.Client
+	registry  *jobRegistry
 	//cronProcessor CronProcessor
 	cronExecutor  CronExecutor
 	mapper        meta.RESTMapper
+	restMapper    *refreshingRESTMapper
 	scaler        scale.ScalesGetter
+	dynamicClient dynamic.Interface
 	eventRecorder record.EventRecorder
+	queue         *executionQueue
+	statusBatcher *statusBatcher
+}
+
+// applyJitter sets the jitter of the job created by a factory before it is checked or submitted.
+func (cm *CronManager) applyJitter(j CronJob) {
+	if job, ok := j.(*CronJobHPA); ok {
+		job.setJitter(cm.options.DefaultJitterSeconds)
+	}
 }
 
 func (cm *CronManager) createOrUpdate(j CronJob) error {
-	if _, ok := cm.jobQueue.Load(j.ID()); !ok {
+	newJob, convert := j.(*CronJobHPA)
+	if !convert {
+		return fmt.Errorf("failed to convert job %v to CronJobHPA", j)
+	}
+	if job, ok := cm.registry.get(j.ID()); !ok {
 		err := cm.cronExecutor.AddJob(j)
 		if err != nil {
 			return fmt.Errorf("Failed to add job to cronExecutor,because of %v", err)
 		}
-		cm.jobQueue.Store(j.ID(), j)
+		cm.registry.add(newJob)
 		log.Infof("cronHPA job %s of cronHPA %s in %s created, %d active jobs exist", j.Name(), j.CronHPAMeta().Name, j.CronHPAMeta().Namespace,
-			queueLength(cm.jobQueue))
+			cm.registry.len())
 	} else {
-		loadJob, _ := cm.jobQueue.Load(j.ID())
-		job, convert := loadJob.(*CronJobHPA)
-		if !convert {
-			return fmt.Errorf("failed to convert job %v to CronJobHPA", loadJob)
-		}
 		if ok := job.Equals(j); !ok {
 			err := cm.cronExecutor.Update(j)
 			if err != nil {
 				return fmt.Errorf("failed to update job %s of cronHPA %s in %s to cronExecutor, because of %v", job.Name(), job.CronHPAMeta().Name, job.CronHPAMeta().Namespace, err)
 			}
-			//update job queue
-			cm.jobQueue.Store(j.ID(), j)
-			log.Infof("cronHPA job %s of cronHPA %s in %s updated, %d active jobs exist", j.Name(), j.CronHPAMeta().Name, j.CronHPAMeta().Namespace, queueLength(cm.jobQueue))
+			//update job registry
+			cm.registry.add(newJob)
+			if job != newJob {
+				job.stopMeasuringReadiness()
+			}
+			log.Infof("cronHPA job %s of cronHPA %s in %s updated, %d active jobs exist", j.Name(), j.CronHPAMeta().Name, j.CronHPAMeta().Namespace, cm.registry.len())
 		} else {
 			return &NoNeedUpdate{}
 		}
@@ -77,34 +107,115 @@ func (cm *CronManager) createOrUpdate(j CronJob) error {
 }
 
 func (cm *CronManager) delete(id string) error {
-	if loadJob, ok := cm.jobQueue.Load(id); ok {
-		j, _ := loadJob.(*CronJobHPA)
+	if j, ok := cm.registry.get(id); ok {
 		err := cm.cronExecutor.RemoveJob(j)
 		if err != nil {
 			return fmt.Errorf("Failed to remove job from cronExecutor,because of %v", err)
 		}
-		cm.jobQueue.Delete(id)
-		log.Infof("Remove cronHPA job %s of cronHPA %s in %s from jobQueue,%d active jobs left", j.Name(), j.CronHPAMeta().Name, j.CronHPAMeta().Namespace, queueLength(cm.jobQueue))
+		cm.registry.remove(id)
+		j.stopMeasuringReadiness()
+		log.Infof("Remove cronHPA job %s of cronHPA %s in %s from job registry,%d active jobs left", j.Name(), j.CronHPAMeta().Name, j.CronHPAMeta().Namespace, cm.registry.len())
 	}
 	return nil
 }
 
+// deleteOwnerJobs removes all the jobs of the cronHPA, or of the cluster policy whose key has no namespace.
+// It is called once the owner is not found, so the jobs don't wait for the GC.
+func (cm *CronManager) deleteOwnerJobs(owner types.NamespacedName) {
+	for _, job := range cm.registry.ofOwner(owner) {
+		if err := cm.delete(job.ID()); err != nil {
+			log.Errorf("Failed to delete job %s of %s in %s, because of %v", job.Name(), owner.Name, job.HPARef.Namespace, err)
+		}
+	}
+}
+
+// JobResultHandler records the result of the job in the status. The results of the jobs of
+// the same object in the batch window are written together.
 func (cm *CronManager) JobResultHandler(js *cron.JobResult) {
 	job := js.Ref.(*CronJobHPA)
-	cronHpa := js.Ref.(*CronJobHPA).HPARef
-	instance := &autoscalingv1beta1.CronHorizontalPodAutoscaler{}
-	e := cm.client.Get(context.TODO(), types.NamespacedName{
-		Namespace: cronHpa.Namespace,
-		Name:      cronHpa.Name,
-	}, instance)
-
-	if e != nil {
-		log.Errorf("Failed to fetch cronHPA job %s of cronHPA %s in namespace %s, because of %v", job.Name(), cronHpa.Name, cronHpa.Namespace, e)
+	// the readiness is measured after the result is recorded.
+	if job.adaptiveLeadTime && js.Error == nil {
+		defer func() {
+			go cm.measureReadiness(job)
+		}()
+	}
+	if job.clusterPolicy != "" {
+		cm.clusterJobResultHandler(job, js)
 		return
 	}
 
-	deepCopy := instance.DeepCopy()
+	cronHpa := job.HPARef
+	condition, eventType := newJobResultCondition(job, js)
+	cm.statusBatcher.add("cronhorizontalpodautoscalers", types.NamespacedName{Namespace: cronHpa.Namespace, Name: cronHpa.Name}, newCronHPAObject, statusUpdate{
+		apply: func(obj client.Object) {
+			instance := obj.(*autoscalingv1beta1.CronHorizontalPodAutoscaler)
+			if job.profile != "" {
+				setProfileResult(instance, job, condition)
+				return
+			}
+			instance.Status.Conditions = setJobCondition(instance.Status.Conditions, condition)
+		},
+		done: func(obj client.Object, err error) {
+			if err != nil {
+				if errors.IsNotFound(err) {
+					log.Warning("No need to update cronHPA, because it is deleted before")
+					return
+				}
+				// the batcher emits the event of the lost updates.
+				log.Errorf("Failed to update cronHPA job %s of cronHPA %s in %s, because of %v", job.Name(), cronHpa.Name, cronHpa.Namespace, err)
+				return
+			}
+			cm.eventRecorder.Event(obj, eventType, string(condition.State), condition.Message)
+		},
+	})
+}
+
+// clusterJobResultHandler records the result in the namespace of the cluster policy.
+func (cm *CronManager) clusterJobResultHandler(job *CronJobHPA, js *cron.JobResult) {
+	namespace := job.HPARef.Namespace
+	condition, eventType := newJobResultCondition(job, js)
+
+	cm.statusBatcher.add("clustercronhorizontalpodautoscalers", types.NamespacedName{Name: job.clusterPolicy}, newClusterCronHPAObject, statusUpdate{
+		apply: func(obj client.Object) {
+			instance := obj.(*autoscalingv1beta1.ClusterCronHorizontalPodAutoscaler)
+			found := false
+			for index, n := range instance.Status.Namespaces {
+				if n.Namespace == namespace {
+					found = true
+					instance.Status.Namespaces[index].Conditions = setJobCondition(n.Conditions, condition)
+				}
+			}
+			if !found {
+				instance.Status.Namespaces = append(instance.Status.Namespaces, autoscalingv1beta1.NamespaceCondition{
+					Namespace:  namespace,
+					Conditions: []autoscalingv1beta1.Condition{condition},
+				})
+			}
+		},
+		done: func(obj client.Object, err error) {
+			if err != nil {
+				if errors.IsNotFound(err) {
+					log.Warning("No need to update clusterCronHPA, because it is deleted before")
+					return
+				}
+				// the batcher emits the event of the lost updates.
+				log.Errorf("Failed to update cronHPA job %s of clusterCronHPA %s in %s, because of %v", job.Name(), job.clusterPolicy, namespace, err)
+				return
+			}
+			cm.eventRecorder.Event(obj, eventType, string(condition.State), fmt.Sprintf("%s namespace: %s", namespace, condition.Message))
+		},
+	})
+}
 
+func newCronHPAObject() client.Object {
+	return &autoscalingv1beta1.CronHorizontalPodAutoscaler{}
+}
+
+func newClusterCronHPAObject() client.Object {
+	return &autoscalingv1beta1.ClusterCronHorizontalPodAutoscaler{}
+}
+
+func newJobResultCondition(job *CronJobHPA, js *cron.JobResult) (autoscalingv1beta1.Condition, string) {
 	var (
 		state     autoscalingv1beta1.JobState
 		message   string
@@ -122,66 +233,49 @@ func (cm *CronManager) JobResultHandler(js *cron.JobResult) {
 		eventType = v1.EventTypeNormal
 	}
 
-	condition := autoscalingv1beta1.Condition{
-		Name:          job.Name(),
-		JobId:         job.ID(),
-		RunOnce:       job.RunOnce,
-		Schedule:      job.SchedulePlan(),
-		TargetSize:    job.DesiredSize,
-		LastProbeTime: metav1.Time{Time: time.Now()},
-		State:         state,
-		Message:       message,
+	// the run is added to the runs of the previous condition by setJobCondition.
+	var runCount int32
+	if err != nil || !strings.HasPrefix(js.Msg, skipMessagePrefix) {
+		runCount = 1
 	}
 
-	conditions := instance.Status.Conditions
+	condition := autoscalingv1beta1.Condition{
+		Name:               job.Name(),
+		JobId:              job.ID(),
+		RunOnce:            job.RunOnce,
+		Schedule:           job.schedule,
+		NormalizedSchedule: job.SchedulePlan(),
+		TargetSize:         job.DesiredSize,
+		RunCount:           runCount,
+		DSTAdjustment:      job.DSTAdjustment(),
+		JitterOffset:       int32(job.jitter / time.Second),
+		LastProbeTime:      metav1.Time{Time: time.Now()},
+		State:              state,
+		Message:            message,
+		Targets:            convertTargetConditions(job.TargetResults()),
+	}
+	job.setFireTimes(&condition, time.Now())
+	return condition, eventType
+}
 
+func setJobCondition(conditions []autoscalingv1beta1.Condition, condition autoscalingv1beta1.Condition) []autoscalingv1beta1.Condition {
 	var found = false
 	for index, c := range conditions {
-		if c.JobId == job.ID() || c.Name == job.Name() {
+		if c.JobId == condition.JobId || c.Name == condition.Name {
 			found = true
-			instance.Status.Conditions[index] = condition
+			condition.RunCount += c.RunCount
+			// the readyDuration is measured after the result.
+			if condition.ReadyDuration == "" {
+				condition.ReadyDuration = c.ReadyDuration
+			}
+			conditions[index] = condition
 		}
 	}
 
 	if !found {
-		instance.Status.Conditions = append(instance.Status.Conditions, condition)
-	}
-
-	err = cm.updateCronHPAStatusWithRetry(instance, deepCopy, job.name)
-	if err != nil {
-		if _, ok := err.(*NoNeedUpdate); ok {
-			log.Warning("No need to update cronHPA, because it is deleted before")
-			return
-		}
-		cm.eventRecorder.Event(instance, v1.EventTypeWarning, "Failed", fmt.Sprintf("Failed to update cronhpa status: %v", err))
-	} else {
-		cm.eventRecorder.Event(instance, eventType, string(state), message)
+		conditions = append(conditions, condition)
 	}
-}
-
-func (cm *CronManager) updateCronHPAStatusWithRetry(instance *autoscalingv1beta1.CronHorizontalPodAutoscaler, deepCopy *autoscalingv1beta1.CronHorizontalPodAutoscaler, jobName string) error {
-	var err error
-	if instance == nil {
-		log.Warning("Failed to patch cronHPA, because instance is deleted")
-		return &NoNeedUpdate{}
-	}
-	for i := 1; i <= MaxRetryTimes; i++ {
-		// leave ResourceVersion = empty
-		err = cm.client.Patch(context.Background(), instance, client.MergeFrom(deepCopy))
-		if err != nil {
-			if errors.IsNotFound(err) {
-				log.Error("Failed to patch cronHPA, because instance is deleted")
-				return &NoNeedUpdate{}
-			}
-			log.Errorf("Failed to patch cronHPA %v, because of %v", instance, err)
-			continue
-		}
-		break
-	}
-	if err != nil {
-		log.Errorf("Failed to update cronHPA job %s of cronHPA %s in %s after %d times, because of %v", jobName, instance.Name, instance.Namespace, MaxRetryTimes, err)
-	}
-	return err
+	return conditions
 }
 
 func (cm *CronManager) Run(stopChan chan struct{}) {
@@ -206,8 +300,9 @@ func (cm *CronManager) gcLoop() {
 }
 
 // GC will collect all jobs which ref is not exists and recycle.
+// The owner of every group of jobs in the registry is fetched once.
 func (cm *CronManager) GC() {
-	current := queueLength(cm.jobQueue)
+	current := cm.registry.len()
 	log.V(2).Infof("Current active jobs: %d,try to clean up the abandon ones.", current)
 
 	// clean up all metrics
@@ -216,126 +311,190 @@ func (cm *CronManager) GC() {
 	KubeFailedJobsInCronEngineTotal.Set(0)
 	KubeExpiredJobsInCronEngineTotal.Set(0)
 
-	gcJobFunc := func(key, j interface{}) bool {
-		hpa := j.(*CronJobHPA).HPARef
-		job := j.(*CronJobHPA)
-		exitsts := true
-		found, reason := cm.cronExecutor.FindJob(job)
-		instance := &autoscalingv1beta1.CronHorizontalPodAutoscaler{}
+	// the entries are listed once instead of once for every job.
+	entries := make(map[string]*cron.Entry)
+	for _, e := range cm.cronExecutor.ListEntries() {
+		entries[e.Job.ID()] = e
+	}
+	for _, jobs := range cm.registry.owners() {
+		cm.gcOwnerJobs(jobs, entries)
+	}
 
-		// check exists first
-		if err := cm.client.Get(context.Background(), types.NamespacedName{
-			Namespace: hpa.Namespace,
-			Name:      hpa.Name,
-		}, instance); err != nil {
-			exitsts = false
-			if errors.IsNotFound(err) {
-				log.Infof("remove job %s(%s) of cronHPA %s in namespace %s", job.Name(), job.SchedulePlan(), hpa.Name, hpa.Namespace)
-				if found {
-					err := cm.cronExecutor.RemoveJob(job)
-					if err != nil {
-						log.Errorf("Failed to gc job %s(%s) of cronHPA %s in namespace %s", job.Name(), job.SchedulePlan(), hpa.Name, hpa.Namespace)
-						return true
-					}
+	left := cm.registry.len()
+	if evicted := icalendars.evict(time.Now()); evicted != 0 {
+		log.V(2).Infof("Evict %d iCalendar sources which are not used since %v.", evicted, icalEvictAfter)
+	}
+
+	// metrics update
+	// set total jobs in cron engine
+	KubeJobsInCronEngineTotal.Set(float64(left))
+
+	log.V(2).Infof("Current active jobs: %d, clean up %d jobs.", left, current-left)
+}
+
+// gcOwnerJobs checks the jobs of the same owner.
+func (cm *CronManager) gcOwnerJobs(jobs []*CronJobHPA, entries map[string]*cron.Entry) {
+	// check exists first
+	instance, err := cm.getJobOwner(jobs[0])
+	if err != nil && !errors.IsNotFound(err) {
+		// ignore other errors
+		log.Errorf("Failed to fetch the owner of cronHPA %s in namespace %s, because of %v", jobs[0].HPARef.Name, jobs[0].HPARef.Namespace, err)
+		return
+	}
+
+	for _, job := range jobs {
+		hpa := job.HPARef
+		found, reason := checkEntry(entries[job.ID()], job)
+
+		if err != nil {
+			log.Infof("remove job %s(%s) of cronHPA %s in namespace %s", job.Name(), job.SchedulePlan(), hpa.Name, hpa.Namespace)
+			if found {
+				err := cm.cronExecutor.RemoveJob(job)
+				if err != nil {
+					log.Errorf("Failed to gc job %s(%s) of cronHPA %s in namespace %s", job.Name(), job.SchedulePlan(), hpa.Name, hpa.Namespace)
+					continue
 				}
-				cm.delete(job.ID())
-				// metrics update
-				// when a job is in cron engine but not in crd.
-				// that means the job has been expired and need to be clean up.
-				KubeExpiredJobsInCronEngineTotal.Add(1)
 			}
-
+			cm.registry.remove(job.ID())
 			// metrics update
-			// ignore other errors
+			// when a job is in cron engine but not in crd.
+			// that means the job has been expired and need to be clean up.
+			KubeExpiredJobsInCronEngineTotal.Add(1)
+			continue
 		}
+
 		if !found {
-			if exitsts {
-				if reason == JobTimeOut {
-					cm.eventRecorder.Event(instance, v1.EventTypeWarning, "OutOfDate", fmt.Sprintf("rerun out of date job: %s", job.Name()))
-					log.Warningf("Failed to find job %s (job id: %s, plan %s) in cronHPA %s in %s in cron engine and rerun the job.", job.Name(), job.ID(), job.SchedulePlan(), hpa.Name, hpa.Namespace)
-					if msg, reRunErr := job.Run(); reRunErr != nil {
-						log.Errorf("failed to rerun out of date job %s (job id: %s, plan %s) in cronHPA %s in %s, msg:%s, err %v",
-							job.Name(), job.ID(), job.SchedulePlan(), hpa.Name, hpa.Namespace, msg, reRunErr)
-					}
+			if reason == JobTimeOut {
+				cm.eventRecorder.Event(instance, v1.EventTypeWarning, "OutOfDate", fmt.Sprintf("rerun out of date job: %s", job.Name()))
+				log.Warningf("Failed to find job %s (job id: %s, plan %s) in cronHPA %s in %s in cron engine and rerun the job.", job.Name(), job.ID(), job.SchedulePlan(), hpa.Name, hpa.Namespace)
+				if msg, reRunErr := cm.queue.run(job); reRunErr != nil {
+					log.Errorf("failed to rerun out of date job %s (job id: %s, plan %s) in cronHPA %s in %s, msg:%s, err %v",
+						job.Name(), job.ID(), job.SchedulePlan(), hpa.Name, hpa.Namespace, msg, reRunErr)
 				}
-
-				log.Warningf("Failed to find job %s of cronHPA %s in %s in cron engine and resubmit the job.", job.Name(), hpa.Name, hpa.Namespace)
-				cm.cronExecutor.AddJob(job)
 			}
 
+			log.Warningf("Failed to find job %s of cronHPA %s in %s in cron engine and resubmit the job.", job.Name(), hpa.Name, hpa.Namespace)
+			// the out of date entry is still in the engine, so it is replaced instead of added twice.
+			cm.cronExecutor.Update(job)
+
 			// metrics update
 			// when one job is not in cron engine but in crd.
 			// That means the job is failed and need to be resubmitted.
 			KubeFailedJobsInCronEngineTotal.Add(1)
 			KubeSubmittedJobsInCronEngineTotal.Add(1)
-		} else {
-			conditions := instance.Status.Conditions
-			for _, c := range conditions {
-				if c.JobId != job.ID() {
-					continue
-				}
-				switch c.State {
-				case autoscalingv1beta1.Succeed:
-					KubeSuccessfulJobsInCronEngineTotal.Add(1)
-				case autoscalingv1beta1.Failed:
-					KubeFailedJobsInCronEngineTotal.Add(1)
-				case autoscalingv1beta1.Submitted:
-					KubeSubmittedJobsInCronEngineTotal.Add(1)
-				default:
-					KubeSubmittedJobsInCronEngineTotal.Add(1)
-				}
-			}
+			continue
+		}
 
+		for _, c := range ownerConditions(instance, hpa.Namespace) {
+			if c.JobId != job.ID() {
+				continue
+			}
+			switch c.State {
+			case autoscalingv1beta1.Succeed:
+				KubeSuccessfulJobsInCronEngineTotal.Add(1)
+			case autoscalingv1beta1.Failed:
+				KubeFailedJobsInCronEngineTotal.Add(1)
+			case autoscalingv1beta1.Submitted:
+				KubeSubmittedJobsInCronEngineTotal.Add(1)
+			default:
+				KubeSubmittedJobsInCronEngineTotal.Add(1)
+			}
 		}
-		return true
 	}
+}
 
-	cm.jobQueue.Range(gcJobFunc)
+// getJobOwner returns the cronHPA or the cluster policy of the job.
+func (cm *CronManager) getJobOwner(job *CronJobHPA) (client.Object, error) {
+	hpa := job.HPARef
+	if job.clusterPolicy == "" {
+		instance := &autoscalingv1beta1.CronHorizontalPodAutoscaler{}
+		err := cm.client.Get(context.Background(), types.NamespacedName{
+			Namespace: hpa.Namespace,
+			Name:      hpa.Name,
+		}, instance)
+		return instance, err
+	}
 
-	left := queueLength(cm.jobQueue)
+	cluster := &autoscalingv1beta1.ClusterCronHorizontalPodAutoscaler{}
+	err := cm.client.Get(context.Background(), types.NamespacedName{Name: job.clusterPolicy}, cluster)
+	return cluster, err
+}
 
-	// metrics update
-	// set total jobs in cron engine
-	KubeJobsInCronEngineTotal.Set(float64(left))
+// ownerConditions returns the conditions of the jobs of the owner in the namespace.
+func ownerConditions(owner client.Object, namespace string) []autoscalingv1beta1.Condition {
+	switch instance := owner.(type) {
+	case *autoscalingv1beta1.CronHorizontalPodAutoscaler:
+		return instance.Status.Conditions
+	case *autoscalingv1beta1.ClusterCronHorizontalPodAutoscaler:
+		for _, n := range instance.Status.Namespaces {
+			if n.Namespace == namespace {
+				return n.Conditions
+			}
+		}
+	}
+	return nil
+}
 
-	log.V(2).Infof("Current active jobs: %d, clean up %d jobs.", left, current-left)
+// deleteClusterPolicyJobs removes all the jobs of the cluster policy.
+func (cm *CronManager) deleteClusterPolicyJobs(name string) {
+	cm.deleteOwnerJobs(types.NamespacedName{Name: name})
 }
 
-func NewCronManager(cfg *rest.Config, client client.Client, recorder record.EventRecorder) *CronManager {
+func NewCronManager(cfg *rest.Config, client client.Client, apiReader client.Reader, recorder record.EventRecorder, options CronManagerOptions) *CronManager {
 	cm := &CronManager{
+		options:       options,
 		cfg:           cfg,
 		client:        client,
-		jobQueue:      &sync.Map{},
+		registry:      newJobRegistry(),
 		eventRecorder: recorder,
 	}
 
-	hpaClient := clientset.NewForConfigOrDie(cm.cfg)
-	discoveryClient := clientset.NewForConfigOrDie(cm.cfg)
-	resources, err := restmapper.GetAPIGroupResources(discoveryClient)
-	if err != nil {
-		log.Fatalf("Failed to get api resources, because of %v", err)
-	}
-	restMapper := restmapper.NewDiscoveryRESTMapper(resources)
-	// change the rest mapper to discovery resources
-	scaleKindResolver := scale.NewDiscoveryScaleKindResolver(hpaClient.Discovery())
-	scaleClient, err := scalelib.NewForConfig(cm.cfg, restMapper, dynamic.LegacyAPIPathResolverFunc, scaleKindResolver)
+	// the jobs share one token bucket for all the calls of the scale path.
+	limiter := newScaleLimiter(options.ScaleQPS, options.ScaleBurst)
+	cm.jobClient = newRateLimitedClient(client, limiter)
+	cm.dynamicClient = newRateLimitedDynamic(dynamic.NewForConfigOrDie(cm.cfg), limiter)
+	// the discovery information is fetched on the first lookup and refreshed when a kind is not found.
+	restMapper := newRefreshingRESTMapper(discovery.NewDiscoveryClientForConfigOrDie(cm.cfg))
+	scaleClient, err := scalelib.NewForConfig(cm.cfg, restMapper, dynamic.LegacyAPIPathResolverFunc, restMapper.scaleKindResolver)
 
 	if err != nil {
 		log.Fatalf("Failed to create scaler client,because of %v", err)
 	}
 
+	cm.restMapper = restMapper
 	cm.mapper = restMapper
-	cm.scaler = scaleClient
+	cm.scaler = newRateLimitedScales(scaleClient, limiter)
 
-	cm.cronExecutor = NewCronHPAExecutor(nil, cm.JobResultHandler)
+	cm.statusBatcher = newStatusBatcher(client, apiReader, recorder, options.StatusBatchWindow)
+	setICalendarReader(apiReader)
+	cm.queue = newExecutionQueue(options.Workers)
+	cm.cronExecutor = NewCronHPAExecutor(nil, cm.JobResultHandler, cm.queue)
 	return cm
 }
 
-func queueLength(que *sync.Map) int64 {
-	len := int64(0)
-	que.Range(func(k, v interface{}) bool {
-		len++
-		return true
-	})
-	return len
+func convertTargetConditions(results []TargetResult) []autoscalingv1beta1.TargetCondition {
+	if len(results) == 0 {
+		return nil
+	}
+	targets := make([]autoscalingv1beta1.TargetCondition, 0, len(results))
+	for _, r := range results {
+		target := autoscalingv1beta1.TargetCondition{
+			ApiVersion: schema.GroupVersion{Group: r.Ref.RefGroup, Version: r.Ref.RefVersion}.String(),
+			Kind:       r.Ref.RefKind,
+			Name:       r.Ref.RefName,
+			TargetSize: r.DesiredSize,
+			Stage:      r.Stage,
+			State:      autoscalingv1beta1.Succeed,
+			Message:    r.Msg,
+		}
+		if r.Skipped {
+			target.State = autoscalingv1beta1.Skipped
+		}
+		if r.Err != nil {
+			target.State = autoscalingv1beta1.Failed
+			target.Message = r.Err.Error()
+		}
+		targets = append(targets, target)
+	}
+	return targets
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/server"
 	"github.com/gorilla/mux"
+	"github.com/ringtail/go-cron"
 	"html/template"
 	"k8s.io/klog/v2"
 	"net/http"
@@ -70,7 +71,22 @@ func (ws *WebServer) handleIndexController(w http.ResponseWriter, r *http.Reques
 }
 
 func (ws *WebServer) handleJobsController(w http.ResponseWriter, r *http.Request) {
-	b, err := json.Marshal(ws.cronManager.cronExecutor.ListEntries())
+	entries := ws.cronManager.cronExecutor.ListEntries()
+	// target=namespace/kind/name lists the entries of the jobs which scale the workload.
+	if target := r.URL.Query().Get("target"); target != "" {
+		ids := make(map[string]bool)
+		for _, job := range ws.cronManager.registry.ofTarget(target) {
+			ids[job.ID()] = true
+		}
+		filtered := make([]*cron.Entry, 0, len(ids))
+		for _, e := range entries {
+			if ids[e.Job.ID()] {
+				filtered = append(filtered, e)
+			}
+		}
+		entries = filtered
+	}
+	b, err := json.Marshal(entries)
 	if err != nil {
 		w.Write([]byte(err.Error()))
 		return
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	"sort"
+)
+
+const defaultDistributionWeight = 1
+
+// distributeTargetSize splits the targetSize of a job across the targets by the distribution policy.
+// The returned sizes are in the same order as refs.
+func distributeTargetSize(total int32, refs []*TargetRef, policy *v1beta1.DistributionPolicy) ([]int32, error) {
+	weights := make([]int64, len(refs))
+	mins := make([]int32, len(refs))
+	for i, ref := range refs {
+		weights[i] = defaultDistributionWeight
+		for _, t := range policy.Targets {
+			if t.Name != ref.RefName {
+				continue
+			}
+			if t.Weight != nil {
+				weights[i] = int64(*t.Weight)
+			}
+			mins[i] = t.MinReplicas
+		}
+	}
+	return distributeReplicas(total, weights, mins)
+}
+
+// distributeReplicas splits total by weights with the largest remainder method.
+// A target whose share is below its minimum gets the minimum and the rest is split
+// again between the others. The minimums which exceed the total are an error, since the targets
+// would be scaled above the targetSize.
+func distributeReplicas(total int32, weights []int64, mins []int32) ([]int32, error) {
+	sizes := make([]int32, len(weights))
+	if len(weights) == 0 {
+		return sizes, nil
+	}
+	sumMins := int64(0)
+	for _, m := range mins {
+		sumMins += int64(m)
+	}
+	if sumMins > int64(total) {
+		return nil, fmt.Errorf("the minReplicas of the targets add up to %d, which is more than the targetSize %d", sumMins, total)
+	}
+
+	pinned := make([]bool, len(weights))
+	for {
+		remaining := int64(total)
+		sumWeights := int64(0)
+		for i := range weights {
+			if pinned[i] {
+				remaining -= int64(mins[i])
+			} else {
+				sumWeights += weights[i]
+			}
+		}
+
+		unpinned := make([]int, 0, len(weights))
+		for i := range weights {
+			if !pinned[i] {
+				unpinned = append(unpinned, i)
+			}
+		}
+		if len(unpinned) == 0 {
+			break
+		}
+
+		// all the left weights are zero, split the rest evenly.
+		weightOf := func(i int) int64 {
+			if sumWeights == 0 {
+				return 1
+			}
+			return weights[i]
+		}
+		if sumWeights == 0 {
+			sumWeights = int64(len(unpinned))
+		}
+
+		shares := make(map[int]int64, len(unpinned))
+		remainders := make(map[int]int64, len(unpinned))
+		assigned := int64(0)
+		if remaining > 0 {
+			for _, i := range unpinned {
+				shares[i] = remaining * weightOf(i) / sumWeights
+				remainders[i] = remaining * weightOf(i) % sumWeights
+				assigned += shares[i]
+			}
+		}
+
+		// hand out the rest one by one to the largest remainders.
+		order := append([]int{}, unpinned...)
+		sort.SliceStable(order, func(a, b int) bool {
+			if remainders[order[a]] != remainders[order[b]] {
+				return remainders[order[a]] > remainders[order[b]]
+			}
+			return weightOf(order[a]) > weightOf(order[b])
+		})
+		for k := 0; remaining > 0 && k < int(remaining-assigned); k++ {
+			shares[order[k%len(order)]]++
+		}
+
+		repin := false
+		for _, i := range unpinned {
+			if shares[i] < int64(mins[i]) {
+				pinned[i] = true
+				repin = true
+			}
+		}
+		if repin {
+			continue
+		}
+
+		for _, i := range unpinned {
+			sizes[i] = int32(shares[i])
+		}
+		break
+	}
+
+	for i := range weights {
+		if pinned[i] {
+			sizes[i] = mins[i]
+		}
+	}
+	return sizes, nil
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	"reflect"
+	"testing"
+)
+
+func TestDistributeReplicas(t *testing.T) {
+	testCases := []struct {
+		name    string
+		total   int32
+		weights []int64
+		mins    []int32
+		sizes   []int32
+		wantErr bool
+	}{
+		{name: "40/30/30", total: 60, weights: []int64{40, 30, 30}, mins: []int32{0, 0, 0}, sizes: []int32{24, 18, 18}},
+		{name: "largest remainder", total: 61, weights: []int64{40, 30, 30}, mins: []int32{0, 0, 0}, sizes: []int32{25, 18, 18}},
+		// the remainders of the last two are equal, the first of them gets the replica.
+		{name: "remainder tie", total: 62, weights: []int64{40, 30, 30}, mins: []int32{0, 0, 0}, sizes: []int32{25, 19, 18}},
+		{name: "equal weights", total: 10, weights: []int64{1, 1, 1}, mins: []int32{0, 0, 0}, sizes: []int32{4, 3, 3}},
+		{name: "minimum", total: 10, weights: []int64{1, 1}, mins: []int32{0, 7}, sizes: []int32{3, 7}},
+		{name: "minimums equal to the total", total: 6, weights: []int64{1, 1}, mins: []int32{3, 3}, sizes: []int32{3, 3}},
+		{name: "minimums above the total", total: 5, weights: []int64{1, 1}, mins: []int32{3, 3}, wantErr: true},
+		{name: "zero weights", total: 10, weights: []int64{0, 0}, mins: []int32{0, 0}, sizes: []int32{5, 5}},
+		{name: "one zero weight", total: 10, weights: []int64{1, 0}, mins: []int32{0, 0}, sizes: []int32{10, 0}},
+		{name: "zero weight with minimum", total: 10, weights: []int64{0, 1}, mins: []int32{2, 0}, sizes: []int32{2, 8}},
+		{name: "zero total", total: 0, weights: []int64{1, 1}, mins: []int32{0, 0}, sizes: []int32{0, 0}},
+		{name: "no targets", total: 10, sizes: []int32{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sizes, err := distributeReplicas(tc.total, tc.weights, tc.mins)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("distributeReplicas() = %v, want an error", sizes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("distributeReplicas() error = %v", err)
+			}
+			if !reflect.DeepEqual(sizes, tc.sizes) {
+				t.Errorf("distributeReplicas() = %v, want %v", sizes, tc.sizes)
+			}
+		})
+	}
+}
+
+func TestDesiredSizesOfUnsatisfiableMinimums(t *testing.T) {
+	weight := int32(1)
+	job := &CronJobHPA{name: "scale-up", DesiredSize: 4, distribution: &v1beta1.DistributionPolicy{
+		Targets: []v1beta1.DistributionTarget{
+			{Name: "zone-a", Weight: &weight, MinReplicas: 3},
+			{Name: "zone-b", MinReplicas: 3},
+		},
+	}}
+	refs := []*TargetRef{{RefName: "zone-a"}, {RefName: "zone-b"}}
+	if sizes, err := job.desiredSizes(refs); err == nil {
+		t.Errorf("desiredSizes() = %v, want an error instead of scaling above the targetSize", sizes)
+	}
+}
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	"github.com/ringtail/go-cron"
+	log "k8s.io/klog/v2"
+	"sort"
+	"time"
+)
+
+const (
+	dstTimeFormat = "2006-01-02T15:04:05"
+	// maxDSTSkips limits the transitions skipped by the policy in one search.
+	maxDSTSkips = 8
+)
+
+// dstSchedule evaluates the schedule by the wall clock of the location and applies the policy
+// to the times which don't exist on a spring-forward day or happen twice on a fall-back day.
+//
+//	policy              | nonexistent time                        | repeated time
+//	runAtNextValidTime  | runs at the first valid time after gap  | runs at the first occurrence
+//	runOnceOnRepeat     | skipped                                 | runs at the first occurrence
+//	skip                | skipped                                 | skipped
+type dstSchedule struct {
+	cron.Schedule
+	location *time.Location
+	policy   v1beta1.DSTPolicy
+	// record is called with the description of the adjustment when the policy is applied.
+	record func(string)
+}
+
+func newDSTSchedule(schedule cron.Schedule, location *time.Location, policy v1beta1.DSTPolicy, record func(string)) *dstSchedule {
+	if location == nil {
+		location = time.Local
+	}
+	if policy == "" {
+		policy = v1beta1.DSTRunAtNextValidTime
+	}
+	return &dstSchedule{Schedule: schedule, location: location, policy: policy, record: record}
+}
+
+func (ds *dstSchedule) Next(t time.Time) time.Time {
+	t = t.In(ds.location)
+	// the inner schedule runs on the wall clock in UTC, which has no transitions.
+	wall := wallClock(t)
+	// the repeated times before the second occurrence of t have run at their first occurrences.
+	if instants := ds.instantsOf(wall); len(instants) == 2 && t.Equal(instants[1]) {
+		wall = ds.repeatEnd(instants[1]).Add(-time.Second)
+	}
+	// every skip moves after a transition, and the transitions of a zone are months apart.
+	for i := 0; i < maxDSTSkips; i++ {
+		wall = ds.Schedule.Next(wall)
+		if wall.IsZero() {
+			return wall
+		}
+		instants := ds.instantsOf(wall)
+		switch len(instants) {
+		case 0:
+			// the zone which begins at the end of the gap.
+			start, _ := ds.afterGap(wall).ZoneBounds()
+			if ds.policy == v1beta1.DSTRunAtNextValidTime {
+				ds.apply(fmt.Sprintf("%s does not exist in %s and the job runs at %s", wall.Format(dstTimeFormat), ds.location, start.Format(time.RFC3339)))
+				return start
+			}
+			ds.apply(fmt.Sprintf("%s does not exist in %s and the job is skipped", wall.Format(dstTimeFormat), ds.location))
+			wall = wallClock(start).Add(-time.Second)
+		case 2:
+			if ds.policy != v1beta1.DSTSkip && instants[0].After(t) {
+				ds.apply(fmt.Sprintf("%s happens twice in %s and the job runs once at %s", wall.Format(dstTimeFormat), ds.location, instants[0].Format(time.RFC3339)))
+				return instants[0]
+			}
+			ds.apply(fmt.Sprintf("%s happens twice in %s and the job is skipped", wall.Format(dstTimeFormat), ds.location))
+			wall = ds.repeatEnd(instants[1]).Add(-time.Second)
+		default:
+			return instants[0]
+		}
+	}
+	return time.Time{}
+}
+
+func (ds *dstSchedule) apply(message string) {
+	log.Infof("dstPolicy %s is applied: %s", ds.policy, message)
+	if ds.record != nil {
+		ds.record(fmt.Sprintf("%s: %s", ds.policy, message))
+	}
+}
+
+// instantsOf returns the instants whose wall clock in the location is the wall time, in order.
+// There is none in a spring-forward gap and two in a fall-back repeat.
+func (ds *dstSchedule) instantsOf(wall time.Time) []time.Time {
+	instants := make([]time.Time, 0, 2)
+	for _, offset := range ds.offsetsAround(wall) {
+		instant := wall.Add(-time.Duration(offset) * time.Second).In(ds.location)
+		if wallClock(instant).Equal(wall) && (len(instants) == 0 || !instants[0].Equal(instant)) {
+			instants = append(instants, instant)
+		}
+	}
+	sort.Slice(instants, func(i, j int) bool {
+		return instants[i].Before(instants[j])
+	})
+	return instants
+}
+
+// offsetsAround returns the offsets of the location half a day before and after the wall time.
+// The transitions of a zone are months apart, so they are the offsets before and after a transition.
+func (ds *dstSchedule) offsetsAround(wall time.Time) []int {
+	_, before := wall.Add(-12 * time.Hour).In(ds.location).Zone()
+	_, after := wall.Add(12 * time.Hour).In(ds.location).Zone()
+	return []int{before, after}
+}
+
+// afterGap returns an instant in the zone which begins at the end of the gap containing the wall time.
+func (ds *dstSchedule) afterGap(wall time.Time) time.Time {
+	before := ds.offsetsAround(wall)[0]
+	return wall.Add(-time.Duration(before) * time.Second).In(ds.location)
+}
+
+// repeatEnd returns the wall time at which the repeated period of the second occurrence ends.
+func (ds *dstSchedule) repeatEnd(second time.Time) time.Time {
+	start, _ := second.ZoneBounds()
+	_, before := start.Add(-time.Second).Zone()
+	_, after := second.Zone()
+	return wallClock(start).Add(time.Duration(before-after) * time.Second)
+}
+
+// wallClock returns the time in UTC with the same wall clock as t.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
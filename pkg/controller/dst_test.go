@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("failed to load location %s: %v", name, err)
+	}
+	return location
+}
+
+func TestDSTScheduleNext(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	sydney := mustLoadLocation(t, "Australia/Sydney")
+
+	testCases := []struct {
+		name       string
+		plan       string
+		location   *time.Location
+		policy     v1beta1.DSTPolicy
+		from       time.Time
+		next       string
+		adjustment string
+	}{
+		// 2026-03-08 02:00 EST jumps to 03:00 EDT in New York.
+		{
+			name:       "spring forward runs at the next valid time",
+			plan:       "0 30 2 * * *",
+			location:   newYork,
+			policy:     v1beta1.DSTRunAtNextValidTime,
+			from:       time.Date(2026, 3, 8, 0, 0, 0, 0, newYork),
+			next:       "2026-03-08T03:00:00-04:00",
+			adjustment: "runAtNextValidTime: 2026-03-08T02:30:00 does not exist in America/New_York and the job runs at 2026-03-08T03:00:00-04:00",
+		},
+		{
+			name:       "spring forward is skipped by runOnceOnRepeat",
+			plan:       "0 30 2 * * *",
+			location:   newYork,
+			policy:     v1beta1.DSTRunOnceOnRepeat,
+			from:       time.Date(2026, 3, 8, 0, 0, 0, 0, newYork),
+			next:       "2026-03-09T02:30:00-04:00",
+			adjustment: "runOnceOnRepeat: 2026-03-08T02:30:00 does not exist in America/New_York and the job is skipped",
+		},
+		{
+			name:       "spring forward is skipped by skip",
+			plan:       "0 30 2 * * *",
+			location:   newYork,
+			policy:     v1beta1.DSTSkip,
+			from:       time.Date(2026, 3, 8, 0, 0, 0, 0, newYork),
+			next:       "2026-03-09T02:30:00-04:00",
+			adjustment: "skip: 2026-03-08T02:30:00 does not exist in America/New_York and the job is skipped",
+		},
+		// 2026-11-01 02:00 EDT falls back to 01:00 EST in New York.
+		{
+			name:       "fall back runs at the first occurrence by runAtNextValidTime",
+			plan:       "0 30 1 * * *",
+			location:   newYork,
+			policy:     v1beta1.DSTRunAtNextValidTime,
+			from:       time.Date(2026, 11, 1, 0, 0, 0, 0, newYork),
+			next:       "2026-11-01T01:30:00-04:00",
+			adjustment: "runAtNextValidTime: 2026-11-01T01:30:00 happens twice in America/New_York and the job runs once at 2026-11-01T01:30:00-04:00",
+		},
+		{
+			name:       "fall back runs at the first occurrence by runOnceOnRepeat",
+			plan:       "0 30 1 * * *",
+			location:   newYork,
+			policy:     v1beta1.DSTRunOnceOnRepeat,
+			from:       time.Date(2026, 11, 1, 0, 0, 0, 0, newYork),
+			next:       "2026-11-01T01:30:00-04:00",
+			adjustment: "runOnceOnRepeat: 2026-11-01T01:30:00 happens twice in America/New_York and the job runs once at 2026-11-01T01:30:00-04:00",
+		},
+		{
+			name:       "fall back doesn't run at the second occurrence",
+			plan:       "0 30 1 * * *",
+			location:   newYork,
+			policy:     v1beta1.DSTRunOnceOnRepeat,
+			from:       time.Date(2026, 11, 1, 1, 30, 0, 0, newYork),
+			next:       "2026-11-02T01:30:00-05:00",
+			adjustment: "",
+		},
+		{
+			name:       "fall back is skipped by skip",
+			plan:       "0 30 1 * * *",
+			location:   newYork,
+			policy:     v1beta1.DSTSkip,
+			from:       time.Date(2026, 11, 1, 0, 0, 0, 0, newYork),
+			next:       "2026-11-02T01:30:00-05:00",
+			adjustment: "skip: 2026-11-01T01:30:00 happens twice in America/New_York and the job is skipped",
+		},
+		// 2026-10-04 02:00 AEST jumps to 03:00 AEDT and 2026-04-05 03:00 AEDT falls back to 02:00 AEST in Sydney.
+		{
+			name:       "southern spring forward runs at the next valid time",
+			plan:       "0 30 2 * * *",
+			location:   sydney,
+			policy:     v1beta1.DSTRunAtNextValidTime,
+			from:       time.Date(2026, 10, 4, 0, 0, 0, 0, sydney),
+			next:       "2026-10-04T03:00:00+11:00",
+			adjustment: "runAtNextValidTime: 2026-10-04T02:30:00 does not exist in Australia/Sydney and the job runs at 2026-10-04T03:00:00+11:00",
+		},
+		{
+			name:       "southern spring forward is skipped by runOnceOnRepeat",
+			plan:       "0 30 2 * * *",
+			location:   sydney,
+			policy:     v1beta1.DSTRunOnceOnRepeat,
+			from:       time.Date(2026, 10, 4, 0, 0, 0, 0, sydney),
+			next:       "2026-10-05T02:30:00+11:00",
+			adjustment: "runOnceOnRepeat: 2026-10-04T02:30:00 does not exist in Australia/Sydney and the job is skipped",
+		},
+		{
+			name:       "southern fall back runs at the first occurrence",
+			plan:       "0 30 2 * * *",
+			location:   sydney,
+			policy:     v1beta1.DSTRunOnceOnRepeat,
+			from:       time.Date(2026, 4, 5, 0, 0, 0, 0, sydney),
+			next:       "2026-04-05T02:30:00+11:00",
+			adjustment: "runOnceOnRepeat: 2026-04-05T02:30:00 happens twice in Australia/Sydney and the job runs once at 2026-04-05T02:30:00+11:00",
+		},
+		{
+			name:       "southern fall back is skipped by skip",
+			plan:       "0 30 2 * * *",
+			location:   sydney,
+			policy:     v1beta1.DSTSkip,
+			from:       time.Date(2026, 4, 5, 0, 0, 0, 0, sydney),
+			next:       "2026-04-06T02:30:00+10:00",
+			adjustment: "skip: 2026-04-05T02:30:00 happens twice in Australia/Sydney and the job is skipped",
+		},
+		{
+			name:     "ordinary day is not adjusted",
+			plan:     "0 30 2 * * *",
+			location: newYork,
+			policy:   v1beta1.DSTSkip,
+			from:     time.Date(2026, 3, 10, 0, 0, 0, 0, newYork),
+			next:     "2026-03-10T02:30:00-04:00",
+		},
+		// @every runs in fixed intervals of the absolute time and bypasses the policy.
+		{
+			name:     "every bypasses runAtNextValidTime",
+			plan:     "@every 1h",
+			location: newYork,
+			policy:   v1beta1.DSTRunAtNextValidTime,
+			from:     time.Date(2026, 3, 8, 1, 30, 0, 0, newYork),
+			next:     "2026-03-08T03:30:00-04:00",
+		},
+		{
+			name:     "every bypasses skip",
+			plan:     "@every 1h",
+			location: newYork,
+			policy:   v1beta1.DSTSkip,
+			from:     time.Date(2026, 3, 8, 1, 30, 0, 0, newYork),
+			next:     "2026-03-08T03:30:00-04:00",
+		},
+		{
+			name:     "every bypasses the repeated hour",
+			plan:     "@every 1h",
+			location: newYork,
+			policy:   v1beta1.DSTSkip,
+			from:     time.Date(2026, 11, 1, 1, 30, 0, 0, newYork),
+			next:     "2026-11-01T01:30:00-05:00",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			job := &CronJobHPA{Plan: tc.plan, location: tc.location, dstPolicy: tc.policy}
+			schedule, err := job.Schedule()
+			if err != nil {
+				t.Fatalf("failed to parse plan %s: %v", tc.plan, err)
+			}
+			next := schedule.Next(tc.from).In(tc.location).Format(time.RFC3339)
+			if next != tc.next {
+				t.Errorf("Next(%s) = %s, want %s", tc.from.Format(time.RFC3339), next, tc.next)
+			}
+			if adjustment := job.DSTAdjustment(); adjustment != tc.adjustment {
+				t.Errorf("DSTAdjustment() = %q, want %q", adjustment, tc.adjustment)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/ringtail/go-cron"
+	log "k8s.io/klog/v2"
+	"strings"
+	"time"
+)
+
+const (
+	isoDateFormat      = "2006-01-02"
+	dateRangeSeparator = ".."
+)
+
+// IsTodayOff tells whether the day of now is excluded. An exclude date is an ISO date
+// like 2026-10-01, an inclusive range like 2026-10-01..2026-10-07 or a cron expression
+// which matches the day. The dates are compared by calendar day in the location of now.
+func IsTodayOff(excludeDates []string, now time.Time) (bool, string) {
+
+	if excludeDates == nil {
+		return false, ""
+	}
+
+	for _, date := range excludeDates {
+		matched, err := matchExcludeDate(strings.TrimSpace(date), now)
+		if err != nil {
+			log.Warningf("Failed to parse schedule %s,and skip this date,because of %v", date, err)
+			continue
+		}
+		if matched {
+			return true, fmt.Sprintf("skip scaling activity,because of excludeDate (%s).", date)
+		}
+	}
+	return false, ""
+}
+
+// The levels of excludeDates which are named in the skip reason.
+const (
+	jobLevel            = "job"
+	cronHPALevel        = "cronHPA"
+	clusterCronHPALevel = "clusterCronHPA"
+	namespaceLevel      = "namespace"
+)
+
+// levelDates are the excludeDates of a job, a cronHPA or a namespace.
+type levelDates struct {
+	level string
+	dates []string
+}
+
+// isTodayOffByLevels checks the levels in order and names the first level which excludes the day of now.
+func isTodayOffByLevels(levels []levelDates, now time.Time) (bool, string) {
+	for _, l := range levels {
+		if skip, date := matchDates(l.dates, now); skip {
+			return true, fmt.Sprintf("skip scaling activity,because of excludeDate (%s) of the %s.", date, l.level)
+		}
+	}
+	return false, ""
+}
+
+// IsTodayIncluded tells whether the day of now is one of the includeDates, which have the same forms as excludeDates.
+func IsTodayIncluded(includeDates []string, now time.Time) (bool, string) {
+	return matchDates(includeDates, now)
+}
+
+// matchDates returns the first date which matches the day of now.
+func matchDates(dates []string, now time.Time) (bool, string) {
+	for _, date := range dates {
+		matched, err := matchExcludeDate(strings.TrimSpace(date), now)
+		if err != nil {
+			log.Warningf("Failed to parse date %s,and skip this date,because of %v", date, err)
+			continue
+		}
+		if matched {
+			return true, date
+		}
+	}
+	return false, ""
+}
+
+func matchExcludeDate(date string, now time.Time) (bool, error) {
+	if strings.Contains(date, dateRangeSeparator) {
+		arr := strings.SplitN(date, dateRangeSeparator, 2)
+		start, err := time.ParseInLocation(isoDateFormat, strings.TrimSpace(arr[0]), now.Location())
+		if err != nil {
+			return false, err
+		}
+		end, err := time.ParseInLocation(isoDateFormat, strings.TrimSpace(arr[1]), now.Location())
+		if err != nil {
+			return false, err
+		}
+		if dayOf(end) < dayOf(start) {
+			return false, fmt.Errorf("the end of range %s is before the start", date)
+		}
+		return dayOf(start) <= dayOf(now) && dayOf(now) <= dayOf(end), nil
+	}
+
+	if day, err := time.ParseInLocation(isoDateFormat, date, now.Location()); err == nil {
+		return dayOf(day) == dayOf(now), nil
+	}
+
+	schedule, err := cron.Parse(date)
+	if err != nil {
+		return false, err
+	}
+	// the first activation since the start of the day is on the same day if the day matches.
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	next := schedule.Next(startOfDay.Add(-time.Second))
+	return !next.IsZero() && dayOf(next.In(now.Location())) == dayOf(now), nil
+}
+
+// dayOf returns the calendar day of t as yyyymmdd.
+func dayOf(t time.Time) int {
+	return t.Year()*10000 + int(t.Month())*100 + t.Day()
+}
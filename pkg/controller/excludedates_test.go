@@ -0,0 +1,228 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchExcludeDate(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	testCases := []struct {
+		name    string
+		date    string
+		now     time.Time
+		matched bool
+		wantErr bool
+	}{
+		{
+			name:    "iso date on the day",
+			date:    "2026-10-01",
+			now:     time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+			matched: true,
+		},
+		{
+			name: "iso date on another day",
+			date: "2026-10-01",
+			now:  time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "range on the first day",
+			date:    "2026-10-01..2026-10-07",
+			now:     time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			matched: true,
+		},
+		{
+			name:    "range in the middle",
+			date:    "2026-10-01..2026-10-07",
+			now:     time.Date(2026, 10, 4, 9, 0, 0, 0, time.UTC),
+			matched: true,
+		},
+		{
+			name:    "range on the last day",
+			date:    "2026-10-01..2026-10-07",
+			now:     time.Date(2026, 10, 7, 23, 59, 59, 0, time.UTC),
+			matched: true,
+		},
+		{
+			name: "range on the day after",
+			date: "2026-10-01..2026-10-07",
+			now:  time.Date(2026, 10, 8, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "range with spaces",
+			date:    "2026-10-01 .. 2026-10-07",
+			now:     time.Date(2026, 10, 3, 0, 0, 0, 0, time.UTC),
+			matched: true,
+		},
+		{
+			name:    "range ending before the start",
+			date:    "2026-10-07..2026-10-01",
+			now:     time.Date(2026, 10, 3, 0, 0, 0, 0, time.UTC),
+			wantErr: true,
+		},
+		{
+			name:    "range with an invalid end",
+			date:    "2026-10-01..tomorrow",
+			now:     time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			wantErr: true,
+		},
+		{
+			name:    "cron on the day",
+			date:    "* * * 15 11 *",
+			now:     time.Date(2026, 11, 15, 18, 0, 0, 0, time.UTC),
+			matched: true,
+		},
+		{
+			name: "cron on another day",
+			date: "* * * 15 11 *",
+			now:  time.Date(2026, 11, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "cron on weekends",
+			date:    "* * * * * 0,6",
+			now:     time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC),
+			matched: true,
+		},
+		{
+			name:    "invalid cron",
+			date:    "not a date",
+			now:     time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC),
+			wantErr: true,
+		},
+		{
+			// 2026-10-01 00:30 in Shanghai is 2026-09-30 16:30 UTC.
+			name:    "iso date just after midnight in the job timezone",
+			date:    "2026-10-01",
+			now:     time.Date(2026, 10, 1, 0, 30, 0, 0, shanghai),
+			matched: true,
+		},
+		{
+			name: "iso date just before midnight in the job timezone",
+			date: "2026-10-01",
+			now:  time.Date(2026, 9, 30, 23, 30, 0, 0, shanghai),
+		},
+		{
+			name: "range ends at midnight in the job timezone",
+			date: "2026-09-25..2026-09-30",
+			now:  time.Date(2026, 10, 1, 0, 30, 0, 0, shanghai),
+		},
+		{
+			name:    "cron just after midnight in the job timezone",
+			date:    "* * * 1 10 *",
+			now:     time.Date(2026, 10, 1, 0, 30, 0, 0, shanghai),
+			matched: true,
+		},
+		{
+			name: "cron just before midnight in the job timezone",
+			date: "* * * 1 10 *",
+			now:  time.Date(2026, 9, 30, 23, 59, 0, 0, shanghai),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			matched, err := matchExcludeDate(tc.date, tc.now)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("matchExcludeDate(%q, %v) error = %v, wantErr %v", tc.date, tc.now, err, tc.wantErr)
+			}
+			if matched != tc.matched {
+				t.Errorf("matchExcludeDate(%q, %v) = %v, want %v", tc.date, tc.now, matched, tc.matched)
+			}
+		})
+	}
+}
+
+func TestIsTodayOff(t *testing.T) {
+	now := time.Date(2026, 10, 3, 10, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name    string
+		dates   []string
+		skip    bool
+		message string
+	}{
+		{
+			name: "nil dates",
+		},
+		{
+			name:  "no date matches",
+			dates: []string{"2026-10-02", "2026-11-01..2026-11-03"},
+		},
+		{
+			name:    "range matches",
+			dates:   []string{"2026-10-02", " 2026-10-01..2026-10-07 "},
+			skip:    true,
+			message: "skip scaling activity,because of excludeDate ( 2026-10-01..2026-10-07 ).",
+		},
+		{
+			name:    "invalid date is skipped",
+			dates:   []string{"2026-13-01", "2026-10-03"},
+			skip:    true,
+			message: "skip scaling activity,because of excludeDate (2026-10-03).",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			skip, message := IsTodayOff(tc.dates, now)
+			if skip != tc.skip || message != tc.message {
+				t.Errorf("IsTodayOff(%v) = (%v, %q), want (%v, %q)", tc.dates, skip, message, tc.skip, tc.message)
+			}
+		})
+	}
+}
+
+func TestIsTodayOffByLevels(t *testing.T) {
+	now := time.Date(2026, 10, 3, 10, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name   string
+		levels []levelDates
+		skip   bool
+		level  string
+	}{
+		{
+			name: "no levels",
+		},
+		{
+			name: "no level matches",
+			levels: []levelDates{
+				{level: jobLevel, dates: []string{"2026-10-04"}},
+				{level: namespaceLevel, dates: []string{"2026-09-01..2026-09-30"}},
+			},
+		},
+		{
+			name: "the first matching level is named",
+			levels: []levelDates{
+				{level: jobLevel, dates: []string{"2026-10-04"}},
+				{level: cronHPALevel, dates: []string{"2026-10-01..2026-10-07"}},
+				{level: namespaceLevel, dates: []string{"2026-10-03"}},
+			},
+			skip:  true,
+			level: cronHPALevel,
+		},
+		{
+			name: "namespace level",
+			levels: []levelDates{
+				{level: jobLevel},
+				{level: namespaceLevel, dates: []string{"* * * 3 10 *"}},
+			},
+			skip:  true,
+			level: namespaceLevel,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			skip, message := isTodayOffByLevels(tc.levels, now)
+			if skip != tc.skip {
+				t.Fatalf("isTodayOffByLevels() = %v, want %v", skip, tc.skip)
+			}
+			if tc.skip && !strings.HasSuffix(message, "of the "+tc.level+".") {
+				t.Errorf("isTodayOffByLevels() message %q doesn't name the level %s", message, tc.level)
+			}
+		})
+	}
+}
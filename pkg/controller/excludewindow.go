@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	"time"
+)
+
+var excludeWindowFormats = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+type excludeWindow struct {
+	name       string
+	start      time.Time
+	duration   time.Duration
+	recurrence v1beta1.ExcludeWindowRecurrence
+	jobs       []string
+}
+
+// newExcludeWindows parses the windows in the location, nil means the timezone of the controller.
+func newExcludeWindows(windows []v1beta1.ExcludeWindow, location *time.Location) ([]excludeWindow, error) {
+	if location == nil {
+		location = time.Local
+	}
+	parsed := make([]excludeWindow, 0, len(windows))
+	for _, w := range windows {
+		if w.Name == "" {
+			return nil, errors.New("name of excludeWindow could not be empty")
+		}
+		start, err := parseWindowTime(w.Start, location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start of excludeWindow %s, because of %v", w.Name, err)
+		}
+		end, err := parseWindowTime(w.End, location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end of excludeWindow %s, because of %v", w.Name, err)
+		}
+		if !end.After(start) {
+			return nil, fmt.Errorf("the end of excludeWindow %s is not after the start", w.Name)
+		}
+		switch w.Recurrence {
+		case "", v1beta1.Daily, v1beta1.Weekly, v1beta1.Monthly, v1beta1.Yearly:
+		default:
+			return nil, fmt.Errorf("unknown recurrence %s of excludeWindow %s", w.Recurrence, w.Name)
+		}
+		parsed = append(parsed, excludeWindow{
+			name:       w.Name,
+			start:      start,
+			duration:   end.Sub(start),
+			recurrence: w.Recurrence,
+			jobs:       w.Jobs,
+		})
+	}
+	return parsed, nil
+}
+
+func parseWindowTime(value string, location *time.Location) (t time.Time, err error) {
+	for _, format := range excludeWindowFormats {
+		if t, err = time.ParseInLocation(format, value, location); err == nil {
+			return t, nil
+		}
+	}
+	return t, err
+}
+
+// appliesTo tells whether the window applies to the job.
+func (w *excludeWindow) appliesTo(jobName string) bool {
+	if len(w.jobs) == 0 {
+		return true
+	}
+	for _, name := range w.jobs {
+		if name == jobName {
+			return true
+		}
+	}
+	return false
+}
+
+// contains tells whether t is within the window or the latest recurrence of it.
+func (w *excludeWindow) contains(t time.Time) bool {
+	t = t.In(w.start.Location())
+	start := w.start
+	hour, min, sec := start.Clock()
+	switch w.recurrence {
+	case v1beta1.Daily:
+		start = time.Date(t.Year(), t.Month(), t.Day(), hour, min, sec, 0, t.Location())
+		if start.After(t) {
+			start = start.AddDate(0, 0, -1)
+		}
+	case v1beta1.Weekly:
+		days := (int(t.Weekday()) - int(w.start.Weekday()) + 7) % 7
+		start = time.Date(t.Year(), t.Month(), t.Day()-days, hour, min, sec, 0, t.Location())
+		if start.After(t) {
+			start = start.AddDate(0, 0, -7)
+		}
+	case v1beta1.Monthly:
+		start = time.Date(t.Year(), t.Month(), clampDay(t.Year(), t.Month(), w.start.Day()), hour, min, sec, 0, t.Location())
+		if start.After(t) {
+			year, month := t.Year(), t.Month()-1
+			if month < time.January {
+				year, month = year-1, time.December
+			}
+			start = time.Date(year, month, clampDay(year, month, w.start.Day()), hour, min, sec, 0, t.Location())
+		}
+	case v1beta1.Yearly:
+		start = time.Date(t.Year(), w.start.Month(), clampDay(t.Year(), w.start.Month(), w.start.Day()), hour, min, sec, 0, t.Location())
+		if start.After(t) {
+			start = time.Date(t.Year()-1, w.start.Month(), clampDay(t.Year()-1, w.start.Month(), w.start.Day()), hour, min, sec, 0, t.Location())
+		}
+	}
+	// the recurrence begins at the start of the window.
+	if start.Before(w.start) {
+		return false
+	}
+	return !t.Before(start) && t.Before(start.Add(w.duration))
+}
+
+// clampDay returns the day in the month, or the last day of the month if the month is shorter,
+// so a window on the 31st recurs on the 30th of April and a window on February 29th on the 28th.
+func clampDay(year int, month time.Month, day int) int {
+	if last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day(); day > last {
+		return last
+	}
+	return day
+}
+
+// inExcludeWindow returns the window of the job which contains t.
+func inExcludeWindow(windows []excludeWindow, jobName string, t time.Time) (bool, string) {
+	for _, w := range windows {
+		if w.appliesTo(jobName) && w.contains(t) {
+			return true, fmt.Sprintf("skip scaling activity,because of excludeWindow (%s).", w.name)
+		}
+	}
+	return false, ""
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	"testing"
+	"time"
+)
+
+func TestExcludeWindowMonthEnd(t *testing.T) {
+	testCases := []struct {
+		name       string
+		start      string
+		end        string
+		recurrence v1beta1.ExcludeWindowRecurrence
+		at         time.Time
+		want       bool
+	}{
+		{name: "31st in April", start: "2026-01-31 22:00", end: "2026-01-31 23:00", recurrence: v1beta1.Monthly,
+			at: time.Date(2026, 4, 30, 22, 30, 0, 0, time.UTC), want: true},
+		{name: "31st doesn't roll over into May", start: "2026-01-31 22:00", end: "2026-01-31 23:00", recurrence: v1beta1.Monthly,
+			at: time.Date(2026, 5, 1, 22, 30, 0, 0, time.UTC), want: false},
+		{name: "30th in February", start: "2026-01-30 22:00", end: "2026-01-30 23:00", recurrence: v1beta1.Monthly,
+			at: time.Date(2026, 2, 28, 22, 30, 0, 0, time.UTC), want: true},
+		{name: "29th in a leap February", start: "2027-01-29 22:00", end: "2027-01-29 23:00", recurrence: v1beta1.Monthly,
+			at: time.Date(2028, 2, 29, 22, 30, 0, 0, time.UTC), want: true},
+		{name: "31st in March", start: "2026-01-31 22:00", end: "2026-01-31 23:00", recurrence: v1beta1.Monthly,
+			at: time.Date(2026, 3, 31, 22, 30, 0, 0, time.UTC), want: true},
+		{name: "31st of December from January", start: "2025-12-31 22:00", end: "2026-01-01 02:00", recurrence: v1beta1.Monthly,
+			at: time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC), want: true},
+		{name: "30th overnight into the 1st", start: "2026-01-30 22:00", end: "2026-01-31 02:00", recurrence: v1beta1.Monthly,
+			at: time.Date(2026, 3, 1, 1, 0, 0, 0, time.UTC), want: true},
+		{name: "February 29th in a common year", start: "2024-02-29 08:00", end: "2024-02-29 20:00", recurrence: v1beta1.Yearly,
+			at: time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC), want: true},
+		{name: "February 29th not on March 1st", start: "2024-02-29 08:00", end: "2024-02-29 20:00", recurrence: v1beta1.Yearly,
+			at: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			windows, err := newExcludeWindows([]v1beta1.ExcludeWindow{{Name: "window", Start: tc.start, End: tc.end, Recurrence: tc.recurrence}}, time.UTC)
+			if err != nil {
+				t.Fatalf("newExcludeWindows() error = %v", err)
+			}
+			if got := windows[0].contains(tc.at); got != tc.want {
+				t.Errorf("contains(%v) = %v, want %v", tc.at, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	log "k8s.io/klog/v2"
+	"sync"
+	"time"
+)
+
+// executionQueue runs the due jobs with a bounded number of workers. The jobs waiting in the queue
+// run in the order of their priority and then the order in which they are due. A job releases its worker
+// while it pauses between the retries and the polls of the readiness, and waits in the queue again
+// with its priority to continue, so the jobs which wait don't hold the workers.
+type executionQueue struct {
+	lock    sync.Mutex
+	tasks   taskHeap
+	seq     uint64
+	workers int
+	running int
+}
+
+type executionTask struct {
+	priority int32
+	seq      uint64
+	ready    chan struct{}
+}
+
+type executionResult struct {
+	msg string
+	err error
+}
+
+// pausingJob releases its worker while it pauses.
+type pausingJob interface {
+	runWithPause(pause func(d time.Duration)) (string, error)
+}
+
+// newExecutionQueue returns the queue with the workers, the jobs run without the queue if workers is 0.
+func newExecutionQueue(workers int) *executionQueue {
+	return &executionQueue{workers: workers}
+}
+
+// run waits for a worker and runs the job with it.
+func (q *executionQueue) run(job CronJob) (string, error) {
+	if q == nil || q.workers <= 0 {
+		return job.Run()
+	}
+	slot := &executionSlot{queue: q}
+	if ch, ok := job.(*CronJobHPA); ok {
+		slot.priority = ch.priority
+	}
+
+	start := time.Now()
+	q.acquire(slot.priority)
+	slot.held = true
+	wait := time.Since(start)
+	KubeJobExecutionQueueWaitSeconds.Observe(wait.Seconds())
+	log.V(2).Infof("cronHPA job %s of cronHPA %s in %s waited %v in the execution queue", job.Name(), job.CronHPAMeta().Name, job.CronHPAMeta().Namespace, wait)
+
+	defer slot.done()
+	result := runWithRecovery(job, slot.pause)
+	return result.msg, result.err
+}
+
+// acquire takes a free worker, or waits in the queue until a worker is handed to it.
+func (q *executionQueue) acquire(priority int32) {
+	q.lock.Lock()
+	if q.running < q.workers && q.tasks.Len() == 0 {
+		q.running++
+		q.lock.Unlock()
+		return
+	}
+	q.seq++
+	task := &executionTask{priority: priority, seq: q.seq, ready: make(chan struct{})}
+	heap.Push(&q.tasks, task)
+	KubeJobsWaitingInExecutionQueue.Set(float64(q.tasks.Len()))
+	q.lock.Unlock()
+	<-task.ready
+}
+
+// release frees the worker and hands the free workers to the first tasks in the queue.
+func (q *executionQueue) release() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.running--
+	for q.running < q.workers && q.tasks.Len() > 0 {
+		task := heap.Pop(&q.tasks).(*executionTask)
+		q.running++
+		close(task.ready)
+	}
+	KubeJobsWaitingInExecutionQueue.Set(float64(q.tasks.Len()))
+}
+
+// executionSlot is the worker of an execution. The targets of a stage are scaled together, so the worker
+// is released by the first of them which pauses and acquired again by the first one which continues.
+type executionSlot struct {
+	lock     sync.Mutex
+	queue    *executionQueue
+	priority int32
+	held     bool
+}
+
+func (s *executionSlot) pause(d time.Duration) {
+	s.lock.Lock()
+	if s.held {
+		s.queue.release()
+		s.held = false
+	}
+	s.lock.Unlock()
+
+	time.Sleep(d)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.held {
+		s.queue.acquire(s.priority)
+		s.held = true
+	}
+}
+
+func (s *executionSlot) done() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.held {
+		s.queue.release()
+		s.held = false
+	}
+}
+
+// runWithRecovery keeps the queue working when the job panics.
+func runWithRecovery(job CronJob, pause func(d time.Duration)) (result executionResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Failed to run cronHPA job %s of cronHPA %s in %s,because of panic %v", job.Name(), job.CronHPAMeta().Name, job.CronHPAMeta().Namespace, r)
+			result = executionResult{err: fmt.Errorf("panic: %v", r)}
+		}
+	}()
+	if pj, ok := job.(pausingJob); ok {
+		msg, err := pj.runWithPause(pause)
+		return executionResult{msg: msg, err: err}
+	}
+	msg, err := job.Run()
+	return executionResult{msg: msg, err: err}
+}
+
+// taskHeap orders the tasks by the higher priority and then the earlier sequence.
+type taskHeap []*executionTask
+
+func (h taskHeap) Len() int { return len(h) }
+
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h taskHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *taskHeap) Push(x interface{}) { *h = append(*h, x.(*executionTask)) }
+
+func (h *taskHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	task := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return task
+}
+
+// queuedJob is scheduled in the engine instead of the job, so the engine waits for the execution queue.
+type queuedJob struct {
+	CronJob
+	queue *executionQueue
+}
+
+func (qj *queuedJob) Run() (string, error) {
+	return qj.queue.run(qj.CronJob)
+}
+
+// MarshalJSON keeps the entries of the debug server in the format of the job.
+func (qj *queuedJob) MarshalJSON() ([]byte, error) {
+	return json.Marshal(qj.CronJob)
+}
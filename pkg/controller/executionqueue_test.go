@@ -0,0 +1,252 @@
+package controller
+
+import (
+	"context"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	autoscalingapi "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// queueJob runs run with the pause of the queue.
+type queueJob struct {
+	CronJob
+	name string
+	run  func(pause func(d time.Duration)) (string, error)
+}
+
+func (j *queueJob) Name() string { return j.name }
+
+func (j *queueJob) CronHPAMeta() *v1beta1.CronHorizontalPodAutoscaler {
+	return &v1beta1.CronHorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: j.name}}
+}
+
+func (j *queueJob) Run() (string, error) { return j.run(time.Sleep) }
+
+func (j *queueJob) runWithPause(pause func(d time.Duration)) (string, error) { return j.run(pause) }
+
+func TestExecutionQueueReleasesWorkerOnPause(t *testing.T) {
+	q := newExecutionQueue(1)
+	started := make(chan struct{})
+	finished := make(chan struct{})
+
+	// the waiting job pauses until the other job is finished, which runs on the only worker in between.
+	waiting := &queueJob{name: "waiting", run: func(pause func(d time.Duration)) (string, error) {
+		close(started)
+		for {
+			select {
+			case <-finished:
+				return "ready", nil
+			default:
+				pause(10 * time.Millisecond)
+			}
+		}
+	}}
+	other := &queueJob{name: "other", run: func(pause func(d time.Duration)) (string, error) {
+		close(finished)
+		return "scaled", nil
+	}}
+
+	results := make(chan string, 2)
+	go func() {
+		msg, _ := q.run(waiting)
+		results <- msg
+	}()
+	<-started
+	go func() {
+		msg, _ := q.run(other)
+		results <- msg
+	}()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-results:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("the paused job holds the worker")
+		}
+	}
+	if q.running != 0 || q.tasks.Len() != 0 {
+		t.Errorf("running %d and waiting %d after the jobs, want 0", q.running, q.tasks.Len())
+	}
+}
+
+func TestExecutionQueueSharesWorkerInExecution(t *testing.T) {
+	q := newExecutionQueue(2)
+	// the targets of a stage pause together, and the execution holds at most one worker.
+	job := &queueJob{name: "stage", run: func(pause func(d time.Duration)) (string, error) {
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 3; j++ {
+					pause(time.Millisecond)
+				}
+			}()
+		}
+		wg.Wait()
+		return "", nil
+	}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.run(job)
+		}()
+	}
+	wg.Wait()
+	if q.running != 0 || q.tasks.Len() != 0 {
+		t.Errorf("running %d and waiting %d after the jobs, want 0", q.running, q.tasks.Len())
+	}
+}
+
+func TestExecutionQueuePriority(t *testing.T) {
+	q := newExecutionQueue(1)
+	q.acquire(0)
+
+	var lock sync.Mutex
+	order := make([]int32, 0)
+	var wg sync.WaitGroup
+	for i, priority := range []int32{0, 5, 1, 5} {
+		wg.Add(1)
+		go func(priority int32) {
+			defer wg.Done()
+			q.acquire(priority)
+			lock.Lock()
+			order = append(order, priority)
+			lock.Unlock()
+			q.release()
+		}(priority)
+		// the tasks are queued one by one, so the tasks of the same priority keep their order.
+		waitQueued(t, q, i+1)
+	}
+	q.release()
+	wg.Wait()
+
+	if want := []int32{5, 5, 1, 0}; !reflect.DeepEqual(order, want) {
+		t.Errorf("jobs run in the order of priorities %v, want %v", order, want)
+	}
+}
+
+func waitQueued(t *testing.T, q *executionQueue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		q.lock.Lock()
+		queued := q.tasks.Len()
+		q.lock.Unlock()
+		if queued == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d tasks are queued, want %d", queued, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// countingLimiter counts the calls which wait for a token.
+type countingLimiter struct {
+	waits int32
+}
+
+func (l *countingLimiter) TryAccept() bool { return true }
+func (l *countingLimiter) Accept()         {}
+func (l *countingLimiter) Stop()           {}
+func (l *countingLimiter) QPS() float32    { return 0 }
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	atomic.AddInt32(&l.waits, 1)
+	return nil
+}
+
+// hpaClient returns the HPA from the cache and accepts its updates.
+type hpaClient struct {
+	client.Client
+	hpa     *autoscalingapi.HorizontalPodAutoscaler
+	updates int
+}
+
+func (c *hpaClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	c.hpa.DeepCopyInto(obj.(*autoscalingapi.HorizontalPodAutoscaler))
+	return nil
+}
+
+func (c *hpaClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.updates++
+	return nil
+}
+
+func TestScaleHPAUpdateIsRateLimited(t *testing.T) {
+	minReplicas := int32(2)
+	c := &hpaClient{hpa: &autoscalingapi.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nginx"},
+		Spec: autoscalingapi.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingapi.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "nginx"},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    10,
+		},
+		Status: autoscalingapi.HorizontalPodAutoscalerStatus{CurrentReplicas: 4},
+	}}
+	limiter := &countingLimiter{}
+	job := &CronJobHPA{name: "scale-down", client: newRateLimitedClient(c, limiter)}
+
+	// the desired size is below minReplicas, so only the HPA is updated.
+	if _, err := job.ScaleHPA(&TargetRef{RefName: "nginx", RefNamespace: "default", RefKind: "HorizontalPodAutoscaler"}, 1); err != nil {
+		t.Fatalf("ScaleHPA() error = %v", err)
+	}
+	if c.updates != 1 || limiter.waits != 1 {
+		t.Errorf("%d updates wait %d times for the limiter, want 1", c.updates, limiter.waits)
+	}
+}
+
+// listDynamic returns an empty list of every resource in every namespace.
+type listDynamic struct {
+	dynamic.Interface
+}
+
+func (d *listDynamic) Resource(resource schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &listResource{}
+}
+
+type listResource struct {
+	dynamic.NamespaceableResourceInterface
+}
+
+func (r *listResource) Namespace(namespace string) dynamic.ResourceInterface { return r }
+
+func (r *listResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	return &unstructured.UnstructuredList{}, nil
+}
+
+func (r *listResource) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return &unstructured.Unstructured{}, nil
+}
+
+func TestDynamicClientIsRateLimited(t *testing.T) {
+	limiter := &countingLimiter{}
+	d := newRateLimitedDynamic(&listDynamic{}, limiter)
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	if _, err := d.Resource(gvr).Namespace("default").List(context.Background(), metav1.ListOptions{LabelSelector: "app=nginx"}); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if _, err := d.Resource(gvr).Namespace("default").Patch(context.Background(), "nginx", types.JSONPatchType, []byte("[]"), metav1.PatchOptions{}); err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+	if limiter.waits != 2 {
+		t.Errorf("the calls wait %d times for the limiter, want 2", limiter.waits)
+	}
+	if _, ok := newRateLimitedDynamic(&listDynamic{}, nil).(*rateLimitedDynamic); ok {
+		t.Errorf("the dynamic client without a limiter is wrapped")
+	}
+}
@@ -0,0 +1,329 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/ringtail/go-cron"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	minScheduleYear = 1970
+	maxScheduleYear = 2099
+	// extendedLookAheadDays limits the search of the next matched day in the years of the schedule.
+	extendedLookAheadDays = 366 * 5
+)
+
+var (
+	domParser = cron.NewParser(cron.Dom)
+	dowParser = cron.NewParser(cron.Dow)
+	dayNames  = map[string]time.Weekday{"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6}
+)
+
+// ParseSchedule parses the schedule of a job. Besides the syntax of go-cron, it supports
+// L, L-n, LW and nW in the day of month, n#k and nL in the day of week and an optional year field.
+// The jobs are validated and scheduled with it, so a schedule is accepted only if it could run.
+func ParseSchedule(spec string) (cron.Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@") {
+		return cron.Parse(spec)
+	}
+	fields := strings.Fields(spec)
+	if len(fields) == 7 || (len(fields) == 6 && (isExtendedField(fields[3], isExtendedDom) || isExtendedField(fields[5], isExtendedDow))) {
+		return parseExtendedSchedule(fields)
+	}
+	return cron.Parse(spec)
+}
+
+func isExtendedField(field string, extended func(string) bool) bool {
+	for _, item := range strings.Split(strings.ToUpper(field), ",") {
+		if extended(item) {
+			return true
+		}
+	}
+	return false
+}
+
+func isExtendedDom(item string) bool {
+	return strings.HasPrefix(item, "L") || strings.HasSuffix(item, "W")
+}
+
+func isExtendedDow(item string) bool {
+	return strings.Contains(item, "#") || (len(item) > 1 && strings.HasSuffix(item, "L"))
+}
+
+// extendedSchedule matches the time of day like a SpecSchedule and the day with the extended fields.
+type extendedSchedule struct {
+	timeOfDay *cron.SpecSchedule
+	month     uint64
+	dom       uint64
+	dow       uint64
+	// the day matches both fields when one of them is "*" or "?", otherwise one of them.
+	domOrDowAny bool
+	domItems    []func(time.Time) bool
+	dowItems    []func(time.Time) bool
+	years       []yearRange
+}
+
+type yearRange struct {
+	from, to, step int
+}
+
+func parseExtendedSchedule(fields []string) (*extendedSchedule, error) {
+	if len(fields) != 6 && len(fields) != 7 {
+		return nil, fmt.Errorf("expected 6 or 7 fields (second minute hour day-of-month month day-of-week [year]), found %d", len(fields))
+	}
+	base, err := cron.Parse(strings.Join([]string{fields[0], fields[1], fields[2], "*", fields[4], "*"}, " "))
+	if err != nil {
+		return nil, err
+	}
+	spec := base.(*cron.SpecSchedule)
+	s := &extendedSchedule{
+		timeOfDay:   newTimeOfDay(spec),
+		month:       spec.Month,
+		domOrDowAny: isAny(fields[3]) || isAny(fields[5]),
+	}
+	if s.dom, s.domItems, err = parseExtendedDom(fields[3]); err != nil {
+		return nil, fmt.Errorf("invalid day of month %s, because of %v", fields[3], err)
+	}
+	if s.dow, s.dowItems, err = parseExtendedDow(fields[5]); err != nil {
+		return nil, fmt.Errorf("invalid day of week %s, because of %v", fields[5], err)
+	}
+	if len(fields) == 7 {
+		if s.years, err = parseYears(fields[6]); err != nil {
+			return nil, fmt.Errorf("invalid year %s, because of %v", fields[6], err)
+		}
+	}
+	return s, nil
+}
+
+func isAny(field string) bool {
+	return field == "*" || field == "?"
+}
+
+// parseExtendedDom returns the bits of the standard items and the matchers of L, L-n, LW and nW.
+func parseExtendedDom(field string) (uint64, []func(time.Time) bool, error) {
+	standard := make([]string, 0)
+	items := make([]func(time.Time) bool, 0)
+	for _, item := range strings.Split(strings.ToUpper(field), ",") {
+		switch {
+		case item == "L":
+			items = append(items, func(t time.Time) bool { return t.Day() == lastDayOf(t) })
+		case item == "LW":
+			items = append(items, func(t time.Time) bool { return t.Day() == lastWeekdayOf(t) })
+		case strings.HasPrefix(item, "L-"):
+			n, err := strconv.Atoi(item[2:])
+			if err != nil || n < 0 || n > 30 {
+				return 0, nil, fmt.Errorf("invalid offset %s", item)
+			}
+			items = append(items, func(t time.Time) bool { return t.Day() == lastDayOf(t)-n })
+		case strings.HasSuffix(item, "W"):
+			n, err := strconv.Atoi(strings.TrimSuffix(item, "W"))
+			if err != nil || n < 1 || n > 31 {
+				return 0, nil, fmt.Errorf("invalid weekday %s", item)
+			}
+			items = append(items, func(t time.Time) bool { return t.Day() == nearestWeekdayOf(t, n) })
+		default:
+			standard = append(standard, item)
+		}
+	}
+	if len(standard) == 0 {
+		return 0, items, nil
+	}
+	s, err := domParser.Parse(strings.Join(standard, ","))
+	if err != nil {
+		return 0, nil, err
+	}
+	return s.(*cron.SpecSchedule).Dom, items, nil
+}
+
+// parseExtendedDow returns the bits of the standard items and the matchers of n#k and nL.
+func parseExtendedDow(field string) (uint64, []func(time.Time) bool, error) {
+	standard := make([]string, 0)
+	items := make([]func(time.Time) bool, 0)
+	for _, item := range strings.Split(strings.ToUpper(field), ",") {
+		switch {
+		case strings.Contains(item, "#"):
+			arr := strings.SplitN(item, "#", 2)
+			weekday, err := parseWeekday(arr[0])
+			if err != nil {
+				return 0, nil, err
+			}
+			k, err := strconv.Atoi(arr[1])
+			if err != nil || k < 1 || k > 5 {
+				return 0, nil, fmt.Errorf("invalid occurrence %s", item)
+			}
+			items = append(items, func(t time.Time) bool { return t.Weekday() == weekday && (t.Day()-1)/7+1 == k })
+		case len(item) > 1 && strings.HasSuffix(item, "L"):
+			weekday, err := parseWeekday(strings.TrimSuffix(item, "L"))
+			if err != nil {
+				return 0, nil, err
+			}
+			items = append(items, func(t time.Time) bool { return t.Weekday() == weekday && t.Day()+7 > lastDayOf(t) })
+		default:
+			standard = append(standard, item)
+		}
+	}
+	if len(standard) == 0 {
+		return 0, items, nil
+	}
+	s, err := dowParser.Parse(strings.Join(standard, ","))
+	if err != nil {
+		return 0, nil, err
+	}
+	return s.(*cron.SpecSchedule).Dow, items, nil
+}
+
+func parseWeekday(value string) (time.Weekday, error) {
+	if weekday, ok := dayNames[value]; ok {
+		return weekday, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 || n > 6 {
+		return 0, fmt.Errorf("invalid day of week %s", value)
+	}
+	return time.Weekday(n), nil
+}
+
+// parseYears parses a year, a range of years and a step like "2026", "2026-2028" and "2026/2".
+func parseYears(field string) ([]yearRange, error) {
+	if isAny(field) {
+		return nil, nil
+	}
+	years := make([]yearRange, 0)
+	for _, item := range strings.Split(field, ",") {
+		r := yearRange{from: minScheduleYear, to: maxScheduleYear, step: 1}
+		arr := strings.SplitN(item, "/", 2)
+		if len(arr) == 2 {
+			step, err := strconv.Atoi(arr[1])
+			if err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step %s", item)
+			}
+			r.step = step
+		}
+		if arr[0] != "*" {
+			bounds := strings.SplitN(arr[0], "-", 2)
+			from, err := strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid year %s", item)
+			}
+			r.from = from
+			if len(bounds) == 2 {
+				if r.to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid year %s", item)
+				}
+			} else if len(arr) == 1 {
+				r.to = from
+			}
+		}
+		if r.from < minScheduleYear || r.to > maxScheduleYear || r.from > r.to {
+			return nil, fmt.Errorf("year %s is out of range %d-%d", item, minScheduleYear, maxScheduleYear)
+		}
+		years = append(years, r)
+	}
+	return years, nil
+}
+
+func (s *extendedSchedule) matchesYear(year int) bool {
+	if len(s.years) == 0 {
+		return true
+	}
+	for _, r := range s.years {
+		if year >= r.from && year <= r.to && (year-r.from)%r.step == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// nextYear returns the first year of the schedule after the year, or 0 if there is none.
+func (s *extendedSchedule) nextYear(year int) int {
+	for y := year + 1; y <= maxScheduleYear; y++ {
+		if s.matchesYear(y) {
+			return y
+		}
+	}
+	return 0
+}
+
+func (s *extendedSchedule) matchesDay(t time.Time) bool {
+	if s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	domMatch := s.dom&(1<<uint(t.Day())) != 0 || matchAny(s.domItems, t)
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0 || matchAny(s.dowItems, t)
+	if s.domOrDowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+func matchAny(items []func(time.Time) bool, t time.Time) bool {
+	for _, match := range items {
+		if match(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the next time after t which matches the schedule, or the zero time if there is none.
+func (s *extendedSchedule) Next(t time.Time) time.Time {
+	candidate := t
+	for days := 0; days < extendedLookAheadDays; {
+		if !s.matchesYear(candidate.Year()) {
+			year := s.nextYear(candidate.Year())
+			if year == 0 {
+				return time.Time{}
+			}
+			candidate = time.Date(year, time.January, 1, 0, 0, 0, 0, candidate.Location()).Add(-time.Second)
+		}
+		candidate = s.timeOfDay.Next(candidate)
+		if candidate.IsZero() {
+			return candidate
+		}
+		if s.matchesYear(candidate.Year()) {
+			if s.matchesDay(candidate) {
+				return candidate
+			}
+			days++
+		}
+		// try the next day.
+		candidate = time.Date(candidate.Year(), candidate.Month(), candidate.Day()+1, 0, 0, 0, 0, candidate.Location()).Add(-time.Second)
+	}
+	return time.Time{}
+}
+
+func lastDayOf(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
+
+func lastWeekdayOf(t time.Time) int {
+	last := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location())
+	for last.Weekday() == time.Saturday || last.Weekday() == time.Sunday {
+		last = last.AddDate(0, 0, -1)
+	}
+	return last.Day()
+}
+
+// nearestWeekdayOf returns the weekday nearest to the day n in the month of t, which never moves to another month.
+func nearestWeekdayOf(t time.Time, n int) int {
+	lastDay := lastDayOf(t)
+	if n > lastDay {
+		return 0
+	}
+	switch time.Date(t.Year(), t.Month(), n, 0, 0, 0, 0, t.Location()).Weekday() {
+	case time.Saturday:
+		if n == 1 {
+			return 3
+		}
+		return n - 1
+	case time.Sunday:
+		if n == lastDay {
+			return n - 2
+		}
+		return n + 1
+	}
+	return n
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	"github.com/ringtail/go-cron"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func TestParseExtendedSchedule(t *testing.T) {
+	// 2026-10-18 is a Sunday.
+	from := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	day := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 8, 0, 0, 0, time.UTC)
+	}
+	testCases := []struct {
+		schedule string
+		next     time.Time
+	}{
+		{schedule: "0 0 8 L * ?", next: day(2026, 10, 31)},
+		{schedule: "0 0 8 L-2 * ?", next: day(2026, 10, 29)},
+		// the last day of October is a Saturday.
+		{schedule: "0 0 8 LW * ?", next: day(2026, 10, 30)},
+		{schedule: "0 0 8 LW 11 ?", next: day(2026, 11, 30)},
+		// November 15th is a Sunday.
+		{schedule: "0 0 8 15W * ?", next: day(2026, 11, 16)},
+		// the weekday of the 1st doesn't move to the previous month.
+		{schedule: "0 0 8 1W * ?", next: day(2026, 11, 2)},
+		{schedule: "0 0 8 ? * MON#2", next: day(2026, 11, 9)},
+		{schedule: "0 0 8 ? * 5#3", next: day(2026, 11, 20)},
+		{schedule: "0 0 8 ? * 5L", next: day(2026, 10, 30)},
+		{schedule: "0 0 8 ? * FRIL", next: day(2026, 10, 30)},
+		{schedule: "0 0 8 L,15 * ?", next: day(2026, 10, 31)},
+		{schedule: "0 0 8 * * ? 2027", next: day(2027, 1, 1)},
+		{schedule: "0 0 8 1 1 ? 2020/3", next: day(2029, 1, 1)},
+		{schedule: "0 0 8 29 2 ? 2027-2030", next: day(2028, 2, 29)},
+		{schedule: "0 0 8 L 2 ? 2027,2028", next: day(2027, 2, 28)},
+		{schedule: "0 0 8 * * ? *", next: day(2026, 10, 19)},
+		// the years are all past, so the schedule never fires.
+		{schedule: "0 0 8 * * * 2020", next: time.Time{}},
+		{schedule: "0 0 8 * * ? 2020-2025", next: time.Time{}},
+		// the day never happens.
+		{schedule: "0 0 8 30 2 ? *", next: time.Time{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.schedule, func(t *testing.T) {
+			schedule, err := ParseSchedule(tc.schedule)
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q) error = %v", tc.schedule, err)
+			}
+			if next := schedule.Next(from); !next.Equal(tc.next) {
+				t.Errorf("Next() of %q = %v, want %v", tc.schedule, next, tc.next)
+			}
+		})
+	}
+}
+
+func TestParseExtendedScheduleErrors(t *testing.T) {
+	for _, schedule := range []string{
+		"0 0 8 * * ? 1969",
+		"0 0 8 * * ? 2100",
+		"0 0 8 * * ? 2026-2100",
+		"0 0 8 * * ? 2028-2026",
+		"0 0 8 * * ? 2026/0",
+		"0 0 8 * * ? next",
+		"0 0 8 L-31 * ?",
+		"0 0 8 0W * ?",
+		"0 0 8 32W * ?",
+		"0 0 8 ? * MON#6",
+		"0 0 8 ? * MON#0",
+		"0 0 8 ? * 7L",
+		"0 0 8 ? * XL",
+		"0 0 8 L * ? 2026 extra",
+	} {
+		if _, err := ParseSchedule(schedule); err == nil {
+			t.Errorf("ParseSchedule(%q) doesn't return an error", schedule)
+		}
+	}
+}
+
+func TestCheckPlanValid(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		plan  string
+		valid bool
+	}{
+		{plan: "0 0 8 * * *", valid: true},
+		{plan: "0 0 8 L * ? 2027", valid: true},
+		{plan: "@every 1h", valid: true},
+		{plan: "0 0 8 * * * 2020", valid: false},
+		{plan: "0 0 8 30 2 ? *", valid: false},
+		{plan: "0 0 8 * * ? 1969", valid: false},
+	}
+	for _, tc := range testCases {
+		if err := checkPlanValid(tc.plan, now); (err == nil) != tc.valid {
+			t.Errorf("checkPlanValid(%q) error = %v, want valid %v", tc.plan, err, tc.valid)
+		}
+	}
+}
+
+func TestCheckEntryOfFinishedSchedule(t *testing.T) {
+	job := &CronJobHPA{id: "past", name: "past", HPARef: &v1beta1.CronHorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cronhpa"}}}
+	// the engine keeps the entry whose schedule has no time left with a zero Next.
+	if found, reason := checkEntry(&cron.Entry{Job: job}, job); !found || reason != "" {
+		t.Errorf("checkEntry() of a finished schedule = %v, %q, want found", found, reason)
+	}
+	if found, reason := checkEntry(&cron.Entry{Job: job, Next: time.Now().Add(-time.Hour)}, job); found || reason != JobTimeOut {
+		t.Errorf("checkEntry() of an out of date entry = %v, %q, want %q", found, reason, JobTimeOut)
+	}
+}
@@ -0,0 +1,415 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	icalDateFormat     = "20060102"
+	icalDateTimeFormat = "20060102T150405"
+	// recurring events without COUNT or UNTIL are expanded for the years ahead.
+	icalRecurrenceYears = 2
+	// maxICalOccurrences limits the occurrences of one recurring event.
+	maxICalOccurrences = 1000
+	// icalRefreshInterval is the age of the parsed data after which it is parsed again,
+	// so the recurring events without COUNT or UNTIL keep being expanded ahead of now.
+	icalRefreshInterval = 24 * time.Hour
+	// icalEvictAfter is the time after which the data of a source which is not loaded any more is evicted.
+	icalEvictAfter = 24 * time.Hour
+)
+
+type icalEntry struct {
+	resourceVersion string
+	dates           []v1beta1.CalendarDate
+	err             error
+	parsed          time.Time
+	lastUsed        time.Time
+}
+
+// icalCache keeps the parsed iCalendar data until the ConfigMap is changed or the data is expired.
+// The ConfigMaps are watched with their metadata only, and the data is read by reader from the API server.
+type icalCache struct {
+	sync.Mutex
+	entries map[string]*icalEntry
+	reader  client.Reader
+}
+
+var icalendars = &icalCache{entries: make(map[string]*icalEntry)}
+
+// setICalendarReader sets the reader of the ConfigMaps, which should bypass the cache. The ConfigMaps
+// are read by the client of the caller if it is not set.
+func setICalendarReader(reader client.Reader) {
+	icalendars.Lock()
+	defer icalendars.Unlock()
+	icalendars.reader = reader
+}
+
+// evict removes the data of the sources which are not loaded since icalEvictAfter.
+func (ic *icalCache) evict(now time.Time) int {
+	ic.Lock()
+	defer ic.Unlock()
+	evicted := 0
+	for source, entry := range ic.entries {
+		if now.Sub(entry.lastUsed) >= icalEvictAfter {
+			delete(ic.entries, source)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// configMapMeta returns the metadata of the ConfigMap, which is served by the metadata informer of the watches.
+func configMapMeta() *metav1.PartialObjectMetadata {
+	meta := &metav1.PartialObjectMetadata{}
+	meta.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+	return meta
+}
+
+func icalSource(namespace string, ref v1beta1.ICalendarRef) string {
+	return fmt.Sprintf("%s/%s/%s", namespace, ref.Name, ref.Key)
+}
+
+// icalEntryKey is the key of the data of the source parsed in the location, since the days of the events depend on it.
+func icalEntryKey(source string, location *time.Location) string {
+	if location == nil {
+		location = time.Local
+	}
+	return source + "@" + location.String()
+}
+
+// loadICalendar returns the dates of the events in the ConfigMap key in the location, nil means the timezone of the controller,
+// and the condition of the source. The ConfigMap is read and parsed again only when its resourceVersion is changed or the data
+// is older than icalRefreshInterval.
+func loadICalendar(ctx context.Context, c client.Reader, namespace string, ref v1beta1.ICalendarRef, location *time.Location) ([]v1beta1.CalendarDate, v1beta1.CalendarCondition) {
+	source := icalSource(namespace, ref)
+	entryKey := icalEntryKey(source, location)
+	condition := v1beta1.CalendarCondition{Source: source}
+	key := types.NamespacedName{Namespace: namespace, Name: ref.Name}
+
+	meta := configMapMeta()
+	if err := c.Get(ctx, key, meta); err != nil {
+		if errors.IsNotFound(err) {
+			icalendars.Lock()
+			delete(icalendars.entries, entryKey)
+			icalendars.Unlock()
+		}
+		condition.Message = fmt.Sprintf("failed to get configmap %s in namespace %s, because of %v", ref.Name, namespace, err)
+		return nil, condition
+	}
+
+	now := time.Now()
+	icalendars.Lock()
+	entry, ok := icalendars.entries[entryKey]
+	reader := icalendars.reader
+	icalendars.Unlock()
+	if !ok || entry.resourceVersion != meta.ResourceVersion || now.Sub(entry.parsed) >= icalRefreshInterval {
+		if reader == nil {
+			reader = c
+		}
+		cm := &corev1.ConfigMap{}
+		if err := reader.Get(ctx, key, cm); err != nil {
+			condition.Message = fmt.Sprintf("failed to get configmap %s in namespace %s, because of %v", ref.Name, namespace, err)
+			return nil, condition
+		}
+		entry = &icalEntry{resourceVersion: cm.ResourceVersion, parsed: now}
+		data, found := cm.Data[ref.Key]
+		if !found {
+			entry.err = fmt.Errorf("key %s is not found in configmap %s", ref.Key, ref.Name)
+		} else {
+			entry.dates, entry.err = parseICalendar(data, now, location)
+		}
+	}
+	icalendars.Lock()
+	entry.lastUsed = now
+	icalendars.entries[entryKey] = entry
+	icalendars.Unlock()
+	condition.ResourceVersion = entry.resourceVersion
+
+	condition.Dates = int32(len(entry.dates))
+	condition.Parsed = entry.err == nil
+	if entry.err != nil {
+		condition.Message = entry.err.Error()
+	}
+	return entry.dates, condition
+}
+
+type icalEvent struct {
+	summary string
+	start   string
+	end     string
+	rrule   string
+	// the TZID parameters of DTSTART and DTEND.
+	startTZID string
+	endTZID   string
+}
+
+// parseICalendar returns the days of the VEVENTs in the location as ISO dates and ranges. It supports
+// all-day and timed events, multi-day events and RRULEs with FREQ, INTERVAL, COUNT and UNTIL.
+// The times in UTC or with a TZID are converted to the location, the all-day and floating times are kept.
+// The events which could not be parsed are reported in the error and the others are returned.
+func parseICalendar(data string, now time.Time, location *time.Location) ([]v1beta1.CalendarDate, error) {
+	if location == nil {
+		location = time.Local
+	}
+	events := make([]icalEvent, 0)
+	var current *icalEvent
+	for _, line := range unfoldICalLines(data) {
+		name, value := splitICalLine(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			current = &icalEvent{}
+		case name == "END" && value == "VEVENT":
+			if current != nil {
+				events = append(events, *current)
+			}
+			current = nil
+		case current == nil:
+			continue
+		case name == "SUMMARY":
+			current.summary = value
+		case name == "DTSTART":
+			current.start = value
+			current.startTZID = icalTZID(line)
+		case name == "DTEND":
+			current.end = value
+			current.endTZID = icalTZID(line)
+		case name == "RRULE":
+			current.rrule = value
+		}
+	}
+
+	dates := make([]v1beta1.CalendarDate, 0, len(events))
+	errs := make([]string, 0)
+	for i, event := range events {
+		eventDates, err := event.dates(now, location)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("event %d (%s): %v", i, event.summary, err))
+			continue
+		}
+		dates = append(dates, eventDates...)
+	}
+	if len(errs) != 0 {
+		return dates, fmt.Errorf("failed to parse %d of %d events, because of [%s]", len(errs), len(events), strings.Join(errs, "; "))
+	}
+	return dates, nil
+}
+
+// unfoldICalLines joins the folded lines which start with a space or a tab.
+func unfoldICalLines(data string) []string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) != 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, "\r"))
+	}
+	return lines
+}
+
+// splitICalLine returns the property name without parameters and the value.
+func splitICalLine(line string) (string, string) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return strings.ToUpper(line), ""
+	}
+	name := line[:i]
+	if j := strings.Index(name, ";"); j >= 0 {
+		name = name[:j]
+	}
+	return strings.ToUpper(strings.TrimSpace(name)), strings.TrimSpace(line[i+1:])
+}
+
+// icalTZID returns the TZID parameter of the property in the line.
+func icalTZID(line string) string {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return ""
+	}
+	for _, param := range strings.Split(line[:i], ";")[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) == 2 && strings.ToUpper(strings.TrimSpace(kv[0])) == "TZID" {
+			return strings.Trim(strings.TrimSpace(kv[1]), `"`)
+		}
+	}
+	return ""
+}
+
+// icalTime returns the time of a DATE or DATE-TIME value. A DATE-TIME with the Z suffix is in UTC and one with
+// a TZID is in that timezone, the DATE and the floating DATE-TIME are in the location.
+func icalTime(value string, tzid string, location *time.Location) (time.Time, error) {
+	if len(value) == len(icalDateFormat) {
+		return time.ParseInLocation(icalDateFormat, value, location)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(icalDateTimeFormat, strings.TrimSuffix(value, "Z"))
+	}
+	if tzid != "" {
+		tz, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown TZID %s", tzid)
+		}
+		location = tz
+	}
+	t, err := time.ParseInLocation(icalDateTimeFormat, value, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %s", value)
+	}
+	return t, nil
+}
+
+// icalDay returns the day of t in the location.
+func icalDay(t time.Time, location *time.Location) time.Time {
+	year, month, day := t.In(location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func (e *icalEvent) dates(now time.Time, location *time.Location) ([]v1beta1.CalendarDate, error) {
+	if e.start == "" {
+		return nil, fmt.Errorf("DTSTART is required")
+	}
+	start, err := icalTime(e.start, e.startTZID, location)
+	if err != nil {
+		return nil, err
+	}
+	// DTEND is not included, the event lasts one day without it.
+	days := 1
+	if e.end != "" {
+		end, err := icalTime(e.end, e.endTZID, location)
+		if err != nil {
+			return nil, err
+		}
+		days = int(icalDay(end, location).Sub(icalDay(start, location)).Hours()/24) + 1
+		// the event which ends at midnight, such as an all-day event, doesn't include the day of DTEND.
+		if local := end.In(location); local.Hour() == 0 && local.Minute() == 0 && local.Second() == 0 && days > 1 {
+			days--
+		}
+		if days < 1 {
+			return nil, fmt.Errorf("DTEND %s is before DTSTART %s", e.end, e.start)
+		}
+	}
+
+	starts := []time.Time{start}
+	if e.rrule != "" {
+		starts, err = expandICalRule(e.rrule, start, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dates := make([]v1beta1.CalendarDate, 0, len(starts))
+	for _, s := range starts {
+		day := icalDay(s, location)
+		date := day.Format(isoDateFormat)
+		if days > 1 {
+			date = date + dateRangeSeparator + day.AddDate(0, 0, days-1).Format(isoDateFormat)
+		}
+		dates = append(dates, v1beta1.CalendarDate{Date: date, Label: e.summary})
+	}
+	return dates, nil
+}
+
+// expandICalRule returns the starts of the occurrences of a simple RRULE in the timezone of the start.
+func expandICalRule(rule string, start time.Time, now time.Time) ([]time.Time, error) {
+	var (
+		freq     string
+		interval = 1
+		count    = 0
+		until    time.Time
+	)
+	for _, part := range strings.Split(rule, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch strings.ToUpper(kv[0]) {
+		case "FREQ":
+			freq = strings.ToUpper(kv[1])
+		case "INTERVAL":
+			n, err := strconv.Atoi(kv[1])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %s", kv[1])
+			}
+			interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(kv[1])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %s", kv[1])
+			}
+			count = n
+		case "UNTIL":
+			t, err := icalTime(kv[1], "", start.Location())
+			if err != nil {
+				return nil, fmt.Errorf("invalid UNTIL %s", kv[1])
+			}
+			// a DATE includes the occurrence on the day.
+			if len(kv[1]) == len(icalDateFormat) {
+				t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+			until = t
+		case "WKST":
+		default:
+			return nil, fmt.Errorf("unsupported RRULE part %s", kv[0])
+		}
+	}
+
+	// next returns the k-th candidate, the months and the years which lack the day of the start
+	// have no occurrence instead of rolling over to the next month.
+	next := func(k int) (time.Time, bool) {
+		switch freq {
+		case "DAILY":
+			return start.AddDate(0, 0, k*interval), true
+		case "WEEKLY":
+			return start.AddDate(0, 0, 7*k*interval), true
+		}
+		year, month := start.Year(), start.Month()
+		if freq == "MONTHLY" {
+			month += time.Month(k * interval)
+		} else {
+			year += k * interval
+		}
+		s := time.Date(year, month, start.Day(), start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+		return s, s.Day() == start.Day()
+	}
+	switch freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return nil, fmt.Errorf("unsupported FREQ %s", freq)
+	}
+	// the rules without COUNT or UNTIL skip the occurrences which started more than a year ago,
+	// so the limit of the occurrences applies to the ones around now.
+	var from time.Time
+	if until.IsZero() && count == 0 {
+		until = now.AddDate(icalRecurrenceYears, 0, 0)
+		from = now.AddDate(-1, 0, 0)
+	}
+
+	starts := make([]time.Time, 0)
+	occurrences := 0
+	for k := 0; len(starts) < maxICalOccurrences; k++ {
+		s, ok := next(k)
+		if (!until.IsZero() && s.After(until)) || (count != 0 && occurrences >= count) {
+			break
+		}
+		if !ok {
+			continue
+		}
+		occurrences++
+		if s.Before(from) {
+			continue
+		}
+		starts = append(starts, s)
+	}
+	return starts, nil
+}
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"context"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testICalendar = "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Standup\nDTSTART;VALUE=DATE:20000103\nRRULE:FREQ=WEEKLY\nEND:VEVENT\nEND:VCALENDAR\n"
+
+// configMapReader serves the metadata of the ConfigMap like the cache, and counts the reads of the full ConfigMap.
+type configMapReader struct {
+	resourceVersion string
+	missing         bool
+	reads           int
+}
+
+func (r *configMapReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if r.missing {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, key.Name)
+	}
+	switch o := obj.(type) {
+	case *metav1.PartialObjectMetadata:
+		o.Namespace = key.Namespace
+		o.Name = key.Name
+		o.ResourceVersion = r.resourceVersion
+	case *corev1.ConfigMap:
+		r.reads++
+		o.Namespace = key.Namespace
+		o.Name = key.Name
+		o.ResourceVersion = r.resourceVersion
+		o.Data = map[string]string{"holidays.ics": testICalendar}
+	}
+	return nil
+}
+
+func (r *configMapReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return nil
+}
+
+func TestLoadICalendarCache(t *testing.T) {
+	defer setICalendarReader(nil)
+	ref := v1beta1.ICalendarRef{Name: "holidays", Key: "holidays.ics"}
+	source := icalEntryKey(icalSource("cache", ref), nil)
+	cache := &configMapReader{resourceVersion: "1"}
+	apiReader := &configMapReader{resourceVersion: "1"}
+	setICalendarReader(apiReader)
+
+	load := func() v1beta1.CalendarCondition {
+		_, condition := loadICalendar(context.Background(), cache, "cache", ref, nil)
+		return condition
+	}
+
+	if condition := load(); !condition.Parsed || condition.Dates == 0 {
+		t.Fatalf("loadICalendar() condition = %+v", condition)
+	}
+	load()
+	if cache.reads != 0 || apiReader.reads != 1 {
+		t.Errorf("the ConfigMap is read %d times from the cache and %d times from the API server, want 0 and 1", cache.reads, apiReader.reads)
+	}
+
+	// a changed ConfigMap is read again.
+	cache.resourceVersion, apiReader.resourceVersion = "2", "2"
+	if condition := load(); condition.ResourceVersion != "2" || apiReader.reads != 2 {
+		t.Errorf("the changed ConfigMap is read %d times, resourceVersion %s", apiReader.reads, condition.ResourceVersion)
+	}
+
+	// the expired data is parsed again.
+	icalendars.Lock()
+	icalendars.entries[source].parsed = time.Now().Add(-icalRefreshInterval)
+	icalendars.Unlock()
+	load()
+	if apiReader.reads != 3 {
+		t.Errorf("the expired data is read %d times, want 3", apiReader.reads)
+	}
+
+	// the data of a deleted ConfigMap is removed.
+	cache.missing = true
+	if condition := load(); condition.Parsed {
+		t.Errorf("loadICalendar() of a deleted ConfigMap is parsed")
+	}
+	icalendars.Lock()
+	_, found := icalendars.entries[source]
+	icalendars.Unlock()
+	if found {
+		t.Errorf("the data of the deleted ConfigMap is kept")
+	}
+}
+
+func TestICalendarCacheEvict(t *testing.T) {
+	now := time.Now()
+	cache := &icalCache{entries: map[string]*icalEntry{
+		"default/used/key":   {lastUsed: now.Add(-time.Hour)},
+		"default/unused/key": {lastUsed: now.Add(-icalEvictAfter)},
+	}}
+	if evicted := cache.evict(now); evicted != 1 {
+		t.Errorf("evict() = %d, want 1", evicted)
+	}
+	if _, found := cache.entries["default/used/key"]; !found || len(cache.entries) != 1 {
+		t.Errorf("entries %v after evict(), want the used one", cache.entries)
+	}
+}
+
+func TestExpandOpenEndedRule(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	starts, err := expandICalRule("FREQ=DAILY", start, now)
+	if err != nil {
+		t.Fatalf("expandICalRule() error = %v", err)
+	}
+	if len(starts) == 0 {
+		t.Fatalf("expandICalRule() returns no occurrence")
+	}
+	// the occurrences since 2000 exceed the limit, the ones around now are kept.
+	first, last := starts[0], starts[len(starts)-1]
+	if first.Before(now.AddDate(-1, 0, 0)) || first.After(now) || !last.After(now) {
+		t.Errorf("expandICalRule() expands from %v to %v, want around %v", first, last, now)
+	}
+
+	// the rules with COUNT start from DTSTART.
+	starts, err = expandICalRule("FREQ=DAILY;COUNT=3", start, now)
+	if err != nil || len(starts) != 3 || !starts[0].Equal(start) {
+		t.Errorf("expandICalRule() with COUNT = %v, %v", starts, err)
+	}
+}
+
+func TestParseICalendarTimezones(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("failed to load Asia/Shanghai, because of %v", err)
+	}
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name  string
+		event string
+		date  string
+	}{
+		{name: "all-day", event: "DTSTART;VALUE=DATE:20261031", date: "2026-10-31"},
+		{name: "floating", event: "DTSTART:20261031T230000", date: "2026-10-31"},
+		// 23:00 UTC is 07:00 of the next day in Shanghai.
+		{name: "utc", event: "DTSTART:20261031T230000Z", date: "2026-11-01"},
+		// 22:00 EDT is 10:00 of the next day in Shanghai.
+		{name: "tzid", event: "DTSTART;TZID=America/New_York:20261031T220000", date: "2026-11-01"},
+		{name: "quoted tzid", event: `DTSTART;TZID="America/New_York":20261031T220000`, date: "2026-11-01"},
+		// the event from 20:00 to 02:00 in UTC is on one day in Shanghai.
+		{name: "utc range", event: "DTSTART:20261031T200000Z\nDTEND:20261101T020000Z", date: "2026-11-01"},
+		{name: "tzid range", event: "DTSTART;TZID=Asia/Tokyo:20261031T000000\nDTEND;TZID=Asia/Tokyo:20261102T000000", date: "2026-10-30..2026-11-01"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Launch\n" + tc.event + "\nEND:VEVENT\nEND:VCALENDAR\n"
+			dates, err := parseICalendar(data, now, shanghai)
+			if err != nil {
+				t.Fatalf("parseICalendar() error = %v", err)
+			}
+			if len(dates) != 1 || dates[0].Date != tc.date {
+				t.Errorf("parseICalendar() = %v, want %s", dates, tc.date)
+			}
+		})
+	}
+
+	data := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART;TZID=Mars/Olympus:20261031T220000\nEND:VEVENT\nEND:VCALENDAR\n"
+	if _, err := parseICalendar(data, now, shanghai); err == nil {
+		t.Errorf("parseICalendar() of an unknown TZID doesn't return an error")
+	}
+}
+
+func TestExpandICalRuleSkipsMissingDays(t *testing.T) {
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		rule  string
+		start time.Time
+		want  []string
+	}{
+		{
+			rule:  "FREQ=MONTHLY;COUNT=4",
+			start: time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC),
+			want:  []string{"2026-01-31", "2026-03-31", "2026-05-31", "2026-07-31"},
+		},
+		{
+			rule:  "FREQ=MONTHLY;INTERVAL=2;UNTIL=20261231",
+			start: time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC),
+			want:  []string{"2025-12-31", "2026-08-31", "2026-10-31", "2026-12-31"},
+		},
+		{
+			rule:  "FREQ=YEARLY;COUNT=2",
+			start: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+			want:  []string{"2024-02-29", "2028-02-29"},
+		},
+	}
+	for _, tc := range testCases {
+		starts, err := expandICalRule(tc.rule, tc.start, now)
+		if err != nil {
+			t.Fatalf("expandICalRule(%s) error = %v", tc.rule, err)
+		}
+		got := make([]string, 0, len(starts))
+		for _, s := range starts {
+			got = append(got, s.Format(isoDateFormat))
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("expandICalRule(%s) = %v, want %v", tc.rule, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/ringtail/go-cron"
+	"hash/fnv"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+// offsetSchedule moves the executions of the schedule by a fixed offset,
+// later by the jitter and earlier by the lead time.
+type offsetSchedule struct {
+	cron.Schedule
+	offset time.Duration
+}
+
+func newOffsetSchedule(schedule cron.Schedule, offset time.Duration) *offsetSchedule {
+	return &offsetSchedule{Schedule: schedule, offset: offset}
+}
+
+func (s *offsetSchedule) Next(t time.Time) time.Time {
+	next := s.Schedule.Next(t.Add(-s.offset))
+	if next.IsZero() {
+		return next
+	}
+	return next.Add(s.offset)
+}
+
+// offset is the time from the time of the schedule to the execution.
+func (ch *CronJobHPA) offset() time.Duration {
+	return ch.jitter - ch.effectiveLeadTime()
+}
+
+// businessTime returns the time of the schedule which the execution at t is for.
+func (ch *CronJobHPA) businessTime(t time.Time) time.Time {
+	return t.Add(-ch.offset())
+}
+
+// jitterOffset returns the offset between 0 and the seconds derived from the identity of the job,
+// so a job keeps its offset across the restarts of the controller while the jobs at the same time are spread.
+func jitterOffset(identity string, seconds int32) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(identity))
+	return time.Duration(h.Sum32()%uint32(seconds+1)) * time.Second
+}
+
+// setJitter sets the offset of the job by its jitterSeconds or the default of the controller.
+func (ch *CronJobHPA) setJitter(defaultSeconds int32) {
+	seconds := defaultSeconds
+	if ch.jitterSeconds != nil {
+		seconds = *ch.jitterSeconds
+	}
+	identity := fmt.Sprintf("%s/%s/%s/%s", ch.clusterPolicy, ch.HPARef.Namespace, ch.HPARef.Name, ch.name)
+	ch.jitter = jitterOffset(identity, seconds)
+}
+
+// nextFireTime returns the time of the next execution after now including the jitter and the lead time, or nil if there is none.
+func (ch *CronJobHPA) nextFireTime(now time.Time) *metav1.Time {
+	schedule, err := ch.Schedule()
+	if err != nil {
+		return nil
+	}
+	next := schedule.Next(now)
+	if next.IsZero() {
+		return nil
+	}
+	return &metav1.Time{Time: next}
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	"github.com/satori/go.uuid"
+	"hash/fnv"
+	"k8s.io/apimachinery/pkg/types"
+	log "k8s.io/klog/v2"
+)
+
+// jobSpec is the part of the spec which a job is created from. The global params whose changes
+// recreate all the jobs are in it, the others are updated in the job by Equals.
+type jobSpec struct {
+	Job                 v1beta1.Job                  `json:"job"`
+	ScaleTargetRef      v1beta1.ScaleTargetRef       `json:"scaleTargetRef"`
+	ScaleTargetRefs     []v1beta1.ScaleTargetRef     `json:"scaleTargetRefs,omitempty"`
+	ScaleTargetSelector *v1beta1.ScaleTargetSelector `json:"scaleTargetSelector,omitempty"`
+	Distribution        *v1beta1.DistributionPolicy  `json:"distribution,omitempty"`
+	Stages              []v1beta1.ScalingStage       `json:"stages,omitempty"`
+	ScaleDownOrder      []string                     `json:"scaleDownOrder,omitempty"`
+	ExcludeWindows      []v1beta1.ExcludeWindow      `json:"excludeWindows,omitempty"`
+	ExcludeCalendars    []string                     `json:"excludeCalendars,omitempty"`
+	IncludeCalendars    []string                     `json:"includeCalendars,omitempty"`
+	Timezone            string                       `json:"timezone,omitempty"`
+	NamespaceDefaults   *v1beta1.NamespaceDefaults   `json:"namespaceDefaults,omitempty"`
+}
+
+// jobID derives the id of a job from the UID of the cronHPA, the namespace, the name of the job and
+// the hash of its spec. The same job gets the same id after the status is lost or the controller restarts,
+// so registering it again is idempotent, and a changed job gets a new id.
+func jobID(instance *v1beta1.CronHorizontalPodAutoscaler, defaults *v1beta1.NamespaceDefaults, job v1beta1.Job) string {
+	spec := jobSpec{
+		Job:                 job,
+		ScaleTargetRef:      instance.Spec.ScaleTargetRef,
+		ScaleTargetRefs:     instance.Spec.ScaleTargetRefs,
+		ScaleTargetSelector: instance.Spec.ScaleTargetSelector,
+		Distribution:        instance.Spec.Distribution,
+		Stages:              instance.Spec.Stages,
+		ScaleDownOrder:      instance.Spec.ScaleDownOrder,
+		ExcludeWindows:      instance.Spec.ExcludeWindows,
+		ExcludeCalendars:    instance.Spec.ExcludeCalendars,
+		IncludeCalendars:    instance.Spec.IncludeCalendars,
+		Timezone:            instance.Spec.Timezone,
+		NamespaceDefaults:   withoutExcludeDates(defaults),
+	}
+	h := fnv.New64a()
+	if data, err := json.Marshal(spec); err == nil {
+		h.Write(data)
+	} else {
+		log.Warningf("Failed to hash the spec of cronHPA job %s of cronHPA %s in namespace %s,because of %v", job.Name, instance.Name, instance.Namespace, err)
+	}
+	name := fmt.Sprintf("%s/%s/%s/%x", instance.UID, instance.Namespace, job.Name, h.Sum64())
+	return uuid.NewV5(uuid.NamespaceOID, name).String()
+}
+
+// deleteJobsExcept removes the jobs of the owner which the owned function selects and are not kept,
+// such as the jobs whose specs are changed when the ids in the status are lost.
+func (cm *CronManager) deleteJobsExcept(owner types.NamespacedName, owned func(job *CronJobHPA) bool, keep map[string]bool) {
+	for _, job := range cm.registry.ofOwner(owner) {
+		if owned(job) && !keep[job.ID()] {
+			if err := cm.delete(job.ID()); err != nil {
+				log.Errorf("Failed to delete orphaned job %s of cronHPA %s in %s, because of %v", job.Name(), job.HPARef.Name, job.HPARef.Namespace, err)
+			}
+		}
+	}
+}
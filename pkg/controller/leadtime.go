@@ -0,0 +1,240 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	autoscalingv1beta1 "github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	log "k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+const (
+	// maxLeadTime limits the leadTime and the adaptive lead time.
+	maxLeadTime = time.Hour
+	// readyMeasureTimeout bounds the measurement of the readiness after a scale-up.
+	readyMeasureTimeout = 30 * time.Minute
+)
+
+// parseLeadTime parses the leadTime of a job, an empty value means no lead time.
+func parseLeadTime(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 || d > maxLeadTime {
+		return 0, fmt.Errorf("leadTime %s is out of range 0-%v", value, maxLeadTime)
+	}
+	return d, nil
+}
+
+// effectiveLeadTime returns the leadTime, or the measured readyDuration rounded up to a minute if it is longer.
+func (ch *CronJobHPA) effectiveLeadTime() time.Duration {
+	lead := ch.leadTime
+	if !ch.adaptiveLeadTime || ch.readyDuration <= 0 {
+		return lead
+	}
+	adaptive := ch.readyDuration.Truncate(time.Minute)
+	if adaptive < ch.readyDuration {
+		adaptive += time.Minute
+	}
+	if adaptive > maxLeadTime {
+		adaptive = maxLeadTime
+	}
+	if adaptive > lead {
+		return adaptive
+	}
+	return lead
+}
+
+// setReadyDuration sets the readyDuration recorded in the condition of the job.
+func (ch *CronJobHPA) setReadyDuration(value string) {
+	if value == "" {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Warningf("Failed to parse readyDuration %s of cronHPA job %s,and ignore it,because of %v", value, ch.name, err)
+		return
+	}
+	ch.readyDuration = d
+}
+
+// setFireTimes sets the lead time and the time of the next execution in the condition of the submitted job.
+func (ch *CronJobHPA) setFireTimes(condition *autoscalingv1beta1.Condition, now time.Time) {
+	condition.LeadTime = ""
+	if lead := ch.effectiveLeadTime(); lead > 0 {
+		condition.LeadTime = lead.String()
+	}
+	condition.NextFireTime = ch.nextFireTime(now)
+	condition.NextBusinessTime = nil
+	if condition.NextFireTime != nil {
+		condition.NextBusinessTime = &metav1.Time{Time: ch.businessTime(condition.NextFireTime.Time)}
+	}
+}
+
+// measureReadiness waits for the targets scaled by the last execution to become ready and records
+// the time in the condition of the job. The targets which are ready at once are not scaled up.
+func (cm *CronManager) measureReadiness(job *CronJobHPA) {
+	job.resultsLock.Lock()
+	results, scaledAt := job.results, job.scaledAt
+	job.resultsLock.Unlock()
+
+	ctx := job.startMeasuringReadiness()
+	readyDuration := pollReadiness(ctx, job.name, results, readyPollInterval, func(ref *TargetRef, desiredSize int32) (bool, error) {
+		return job.isReady(ref, desiredSize, true)
+	}, scaledAt)
+	if ctx.Err() == context.Canceled {
+		log.Infof("Stop measuring the readiness of the targets of cronHPA job %s of cronHPA %s in namespace %s,because the job is deleted or updated", job.name, job.HPARef.Name, job.HPARef.Namespace)
+		return
+	}
+	if readyDuration <= 0 {
+		return
+	}
+	readyDuration = readyDuration.Truncate(time.Second)
+	log.Infof("The targets of cronHPA job %s of cronHPA %s in namespace %s became ready in %v", job.name, job.HPARef.Name, job.HPARef.Namespace, readyDuration)
+	cm.updateJobCondition(job, func(c *autoscalingv1beta1.Condition) {
+		c.ReadyDuration = readyDuration.String()
+	})
+}
+
+// pollReadiness polls the targets of the results together until all of them are ready or the ctx is done,
+// and returns the longest time from scaledAt to the readiness of the targets which were not ready at once.
+func pollReadiness(ctx context.Context, jobName string, results []TargetResult, interval time.Duration,
+	isReady func(ref *TargetRef, desiredSize int32) (bool, error), scaledAt time.Time) time.Duration {
+	pending := make(map[int]bool)
+	for i, r := range results {
+		if r.Err == nil && !r.Skipped && r.DesiredSize > 0 {
+			pending[i] = true
+		}
+	}
+	if len(pending) == 0 {
+		return 0
+	}
+
+	var readyDuration time.Duration
+	scaledUp := make(map[int]bool)
+	first := true
+	wait.PollImmediateUntil(interval, func() (bool, error) {
+		for i := range pending {
+			r := results[i]
+			ready, err := isReady(r.Ref, r.DesiredSize)
+			if err != nil {
+				log.Warningf("Failed to measure the readiness of %s %s in namespace %s for cronHPA job %s,because of %v", r.Ref.RefKind, r.Ref.RefName, r.Ref.RefNamespace, jobName, err)
+				delete(pending, i)
+				continue
+			}
+			if !ready {
+				if first {
+					scaledUp[i] = true
+				}
+				continue
+			}
+			delete(pending, i)
+			if d := time.Since(scaledAt); scaledUp[i] && d > readyDuration {
+				readyDuration = d
+			}
+		}
+		first = false
+		return len(pending) == 0, nil
+	}, ctx.Done())
+
+	if ctx.Err() == context.DeadlineExceeded {
+		for i := range pending {
+			r := results[i]
+			log.Warningf("Failed to measure the readiness of %s %s in namespace %s for cronHPA job %s,because it is not ready in %v", r.Ref.RefKind, r.Ref.RefName, r.Ref.RefNamespace, jobName, readyMeasureTimeout)
+		}
+	}
+	return readyDuration
+}
+
+// startMeasuringReadiness stops the last measurement of the readiness of the job and returns the context of a new one,
+// which is done after the readyMeasureTimeout or once the job is deleted or updated.
+func (ch *CronJobHPA) startMeasuringReadiness() context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), readyMeasureTimeout)
+	ch.resultsLock.Lock()
+	defer ch.resultsLock.Unlock()
+	if ch.stopReadiness != nil {
+		ch.stopReadiness()
+	}
+	ch.stopReadiness = cancel
+	return ctx
+}
+
+// stopMeasuringReadiness stops the measurement of the readiness of the job if there is one.
+func (ch *CronJobHPA) stopMeasuringReadiness() {
+	ch.resultsLock.Lock()
+	defer ch.resultsLock.Unlock()
+	if ch.stopReadiness != nil {
+		ch.stopReadiness()
+		ch.stopReadiness = nil
+	}
+}
+
+// holdScaleDown pauses the job which fired earlier by the lead time until the time it would fire without it,
+// unless the job scales the targets above their current replicas, since the lead time is for the scale-ups only.
+func (ch *CronJobHPA) holdScaleDown(refs []*TargetRef, sizes []int32, firedAt time.Time, pause func(d time.Duration)) {
+	lead := ch.effectiveLeadTime()
+	if lead <= 0 {
+		return
+	}
+	current, desired, err := ch.totalReplicas(refs, sizes)
+	if err != nil {
+		log.Warningf("Failed to get current replicas of the targets of cronHPA job %s and keep the lead time,because of %v", ch.name, err)
+		return
+	}
+	if desired > current {
+		return
+	}
+	if d := firedAt.Add(lead).Sub(ch.now()); d > 0 {
+		log.Infof("cronHPA job %s of cronHPA %s in namespace %s doesn't scale up, and waits %v for the time of the schedule", ch.name, ch.HPARef.Name, ch.HPARef.Namespace, d)
+		pause(d)
+	}
+}
+
+// updateJobCondition changes the condition of the job in the cronHPA or the cluster policy.
+func (cm *CronManager) updateJobCondition(job *CronJobHPA, update func(c *autoscalingv1beta1.Condition)) {
+	hpa := job.HPARef
+	done := func(obj client.Object, err error) {
+		if err != nil {
+			log.Errorf("Failed to update the condition of cronHPA job %s of cronHPA %s in namespace %s,because of %v", job.name, hpa.Name, hpa.Namespace, err)
+		}
+	}
+	if job.clusterPolicy == "" {
+		cm.statusBatcher.add("cronhorizontalpodautoscalers", types.NamespacedName{Namespace: hpa.Namespace, Name: hpa.Name}, newCronHPAObject, statusUpdate{
+			apply: func(obj client.Object) {
+				instance := obj.(*autoscalingv1beta1.CronHorizontalPodAutoscaler)
+				for i := range instance.Status.Conditions {
+					if instance.Status.Conditions[i].Name == job.name {
+						update(&instance.Status.Conditions[i])
+					}
+				}
+			},
+			done: done,
+		})
+		return
+	}
+
+	cm.statusBatcher.add("clustercronhorizontalpodautoscalers", types.NamespacedName{Name: job.clusterPolicy}, newClusterCronHPAObject, statusUpdate{
+		apply: func(obj client.Object) {
+			instance := obj.(*autoscalingv1beta1.ClusterCronHorizontalPodAutoscaler)
+			for _, n := range instance.Status.Namespaces {
+				if n.Namespace != hpa.Namespace {
+					continue
+				}
+				for i := range n.Conditions {
+					if n.Conditions[i].Name == job.name {
+						update(&n.Conditions[i])
+					}
+				}
+			}
+		},
+		done: done,
+	})
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	autoscalingapi "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakescale "k8s.io/client-go/scale/fake"
+	core "k8s.io/client-go/testing"
+	"testing"
+	"time"
+)
+
+func TestPollReadinessPollsTargetsTogether(t *testing.T) {
+	results := []TargetResult{
+		{Ref: &TargetRef{RefKind: "Deployment", RefName: "slow"}, DesiredSize: 3},
+		{Ref: &TargetRef{RefKind: "Deployment", RefName: "never"}, DesiredSize: 3},
+		{Ref: &TargetRef{RefKind: "Deployment", RefName: "ready"}, DesiredSize: 3},
+		{Ref: &TargetRef{RefKind: "Deployment", RefName: "skipped"}, DesiredSize: 3, Skipped: true},
+	}
+	polls := make(map[string]int)
+	isReady := func(ref *TargetRef, desiredSize int32) (bool, error) {
+		polls[ref.RefName]++
+		switch ref.RefName {
+		case "slow":
+			return polls[ref.RefName] > 2, nil
+		case "ready":
+			return true, nil
+		}
+		return false, nil
+	}
+
+	// the target which is never ready doesn't delay the others beyond the deadline of the measurement.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	readyDuration := pollReadiness(ctx, "scale-up", results, 5*time.Millisecond, isReady, started)
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("pollReadiness() takes %v", elapsed)
+	}
+	if readyDuration <= 0 || readyDuration > time.Second {
+		t.Errorf("pollReadiness() = %v, want the time of the slow target", readyDuration)
+	}
+	if polls["ready"] != 1 || polls["slow"] != 3 || polls["never"] < 3 || polls["skipped"] != 0 {
+		t.Errorf("polls = %v", polls)
+	}
+}
+
+func TestStopMeasuringReadiness(t *testing.T) {
+	newJob := func(priority int32) *CronJobHPA {
+		return &CronJobHPA{id: "scale-up", name: "scale-up", Plan: "0 0 8 * * *", priority: priority,
+			HPARef: &v1beta1.CronHorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cronhpa"}}}
+	}
+	cm := &CronManager{registry: newJobRegistry(), cronExecutor: &profileExecutor{}}
+	job := newJob(0)
+	if err := cm.createOrUpdate(job); err != nil {
+		t.Fatalf("createOrUpdate() error = %v", err)
+	}
+
+	isDone := func(ctx context.Context) bool {
+		select {
+		case <-ctx.Done():
+			return ctx.Err() == context.Canceled
+		case <-time.After(time.Second):
+			return false
+		}
+	}
+
+	// a new measurement stops the last one.
+	last := job.startMeasuringReadiness()
+	ctx := job.startMeasuringReadiness()
+	if !isDone(last) {
+		t.Errorf("the last measurement is not stopped by a new one")
+	}
+
+	// the updated job replaces the registered one.
+	if err := cm.createOrUpdate(newJob(1)); err != nil {
+		t.Fatalf("createOrUpdate() error = %v", err)
+	}
+	if !isDone(ctx) {
+		t.Errorf("the measurement is not stopped once the job is updated")
+	}
+
+	registered, _ := cm.registry.get("scale-up")
+	ctx = registered.startMeasuringReadiness()
+	if err := cm.delete("scale-up"); err != nil {
+		t.Fatalf("delete() error = %v", err)
+	}
+	if !isDone(ctx) {
+		t.Errorf("the measurement is not stopped once the job is deleted")
+	}
+}
+
+func TestHoldScaleDown(t *testing.T) {
+	scaler := &fakescale.FakeScaleClient{}
+	scaler.AddReactor("get", "*", func(action core.Action) (bool, runtime.Object, error) {
+		return true, &autoscalingapi.Scale{Spec: autoscalingapi.ScaleSpec{Replicas: 5}}, nil
+	})
+	refs := []*TargetRef{{RefName: "nginx", RefNamespace: "default", RefKind: "Deployment", RefGroup: "apps", RefVersion: "v1"}}
+
+	testCases := []struct {
+		name     string
+		leadTime time.Duration
+		size     int32
+		paused   bool
+	}{
+		{name: "scale-up", leadTime: 5 * time.Minute, size: 8, paused: false},
+		{name: "scale-down", leadTime: 5 * time.Minute, size: 3, paused: true},
+		{name: "same size", leadTime: 5 * time.Minute, size: 5, paused: true},
+		{name: "no lead time", size: 3, paused: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			job := &CronJobHPA{name: tc.name, leadTime: tc.leadTime, scaler: scaler, mapper: newTestMapper(),
+				HPARef: &v1beta1.CronHorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cronhpa"}}}
+			var paused time.Duration
+			job.holdScaleDown(refs, []int32{tc.size}, job.now(), func(d time.Duration) { paused += d })
+			if tc.paused != (paused > 0) {
+				t.Fatalf("holdScaleDown() pauses %v, want paused %v", paused, tc.paused)
+			}
+			// the job fires at the time of the schedule like a job without the lead time.
+			if tc.paused && (paused > tc.leadTime || paused < tc.leadTime-time.Minute) {
+				t.Errorf("holdScaleDown() pauses %v, want the lead time %v", paused, tc.leadTime)
+			}
+		})
+	}
+}
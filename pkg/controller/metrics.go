@@ -45,8 +45,46 @@ var (
 		Help:        "Failed jobs in queue of Cron Engine",
 		ConstLabels: map[string]string{},
 	})
+
+	KubeJobsWaitingInExecutionQueue = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "kube_jobs_waiting_in_execution_queue",
+		Help:        "Due jobs waiting for a worker in the execution queue",
+		ConstLabels: map[string]string{},
+	})
+
+	KubeJobExecutionQueueWaitSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:        "kube_job_execution_queue_wait_seconds",
+		Help:        "Time which the due jobs wait in the execution queue",
+		ConstLabels: map[string]string{},
+		Buckets:     []float64{0.01, 0.05, 0.1, 0.5, 1, 2, 5, 10, 30, 60, 120, 300},
+	})
+
+	KubeAPICallsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:        "kube_api_calls_total",
+		Help:        "Calls to the API server by the executions of the jobs and the status writes, the reads served by the informers are not counted",
+		ConstLabels: map[string]string{},
+	}, []string{"verb", "resource"})
+
+	KubeAPICallsPerJobExecution = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:        "kube_api_calls_per_job_execution",
+		Help:        "Calls to the API server by one execution of a job",
+		ConstLabels: map[string]string{},
+		Buckets:     []float64{0, 1, 2, 3, 5, 10, 20, 50, 100},
+	})
+
+	KubeStatusUpdatesPerWrite = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:        "kube_status_updates_per_write",
+		Help:        "Job results coalesced into one status write",
+		ConstLabels: map[string]string{},
+		Buckets:     []float64{1, 2, 5, 10, 20, 50, 100},
+	})
 )
 
+// countAPICall counts a call to the API server.
+func countAPICall(verb string, resource string) {
+	KubeAPICallsTotal.WithLabelValues(verb, resource).Inc()
+}
+
 func init() {
 	// Register custom metrics with the global prometheus registry
 	metrics.Registry.MustRegister(KubeJobsInCronEngineTotal)
@@ -56,4 +94,9 @@ func init() {
 	metrics.Registry.MustRegister(KubeSuccessfulJobsInCronEngineTotal)
 	metrics.Registry.MustRegister(KubeFailedJobsInCronEngineTotal)
 	metrics.Registry.MustRegister(KubeExpiredJobsInCronEngineTotal)
+	metrics.Registry.MustRegister(KubeJobsWaitingInExecutionQueue)
+	metrics.Registry.MustRegister(KubeJobExecutionQueueWaitSeconds)
+	metrics.Registry.MustRegister(KubeAPICallsTotal)
+	metrics.Registry.MustRegister(KubeAPICallsPerJobExecution)
+	metrics.Registry.MustRegister(KubeStatusUpdatesPerWrite)
 }
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The annotations of a namespace which supply the defaults of the cronHPAs in it.
+const (
+	NamespaceTimezoneAnnotation     = "autoscaling.alibabacloud.com/cronhpa-timezone"
+	NamespaceExcludeDatesAnnotation = "autoscaling.alibabacloud.com/cronhpa-exclude-dates"
+	NamespaceDisabledAnnotation     = "autoscaling.alibabacloud.com/cronhpa-disabled"
+)
+
+// getNamespaceDefaults returns the defaults of the namespace, or nil if it has none.
+func getNamespaceDefaults(ctx context.Context, c client.Client, namespace string) (*v1beta1.NamespaceDefaults, error) {
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return nil, err
+	}
+	return parseNamespaceDefaults(ns), nil
+}
+
+// parseNamespaceDefaults reads the annotations of the namespace.
+// The exclude dates are separated by ";" or new lines.
+func parseNamespaceDefaults(ns *corev1.Namespace) *v1beta1.NamespaceDefaults {
+	annotations := ns.GetAnnotations()
+	defaults := &v1beta1.NamespaceDefaults{
+		Timezone: strings.TrimSpace(annotations[NamespaceTimezoneAnnotation]),
+	}
+	for _, date := range strings.FieldsFunc(annotations[NamespaceExcludeDatesAnnotation], func(r rune) bool {
+		return r == ';' || r == '\n'
+	}) {
+		if date = strings.TrimSpace(date); date != "" {
+			defaults.ExcludeDates = append(defaults.ExcludeDates, date)
+		}
+	}
+	if disabled, err := strconv.ParseBool(strings.TrimSpace(annotations[NamespaceDisabledAnnotation])); err == nil {
+		defaults.Disabled = disabled
+	}
+
+	if defaults.Timezone == "" && len(defaults.ExcludeDates) == 0 && !defaults.Disabled {
+		return nil
+	}
+	return defaults
+}
+
+// namespaceDefaultsChanged tells whether the defaults in the annotations of a namespace are changed.
+func namespaceDefaultsChanged(oldObj, newObj client.Object) bool {
+	oldNs, ok := oldObj.(*corev1.Namespace)
+	if !ok {
+		return false
+	}
+	newNs, ok := newObj.(*corev1.Namespace)
+	if !ok {
+		return false
+	}
+	return !apiequality.Semantic.DeepEqual(parseNamespaceDefaults(oldNs), parseNamespaceDefaults(newNs))
+}
+
+// withoutExcludeDates returns the defaults which need the jobs to be recreated,
+// the excludeDates are updated in the jobs in place.
+func withoutExcludeDates(defaults *v1beta1.NamespaceDefaults) *v1beta1.NamespaceDefaults {
+	if defaults == nil || (defaults.Timezone == "" && !defaults.Disabled) {
+		return nil
+	}
+	return &v1beta1.NamespaceDefaults{Timezone: defaults.Timezone, Disabled: defaults.Disabled}
+}
+
+// loadTimezone returns the location of the cronHPA timezone, which overrides the namespace default.
+// nil means the timezone of the controller.
+func loadTimezone(timezone string, defaults *v1beta1.NamespaceDefaults) (*time.Location, error) {
+	if timezone == "" && defaults != nil {
+		timezone = defaults.Timezone
+	}
+	if timezone == "" {
+		return nil, nil
+	}
+	return time.LoadLocation(timezone)
+}
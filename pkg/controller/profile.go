@@ -0,0 +1,235 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	log "k8s.io/klog/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// profileJobPrefix begins the names of the jobs expanded from the breakpoints of a profile.
+	profileJobPrefix = "profile: "
+	// profileLookBackDays covers the last execution of a weekly breakpoint.
+	profileLookBackDays = 8
+)
+
+// breakpoint is an item of a profile like "08:00=10" or "MON-FRI 08:00:30=10".
+type breakpoint struct {
+	key        string
+	schedule   string
+	targetSize int32
+}
+
+// parseProfile returns the valid breakpoints of the profile, and an error of the invalid ones if any.
+// The first one of the duplicated breakpoints is kept.
+func parseProfile(profile []string) ([]breakpoint, error) {
+	breakpoints := make([]breakpoint, 0, len(profile))
+	invalid := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, item := range profile {
+		bp, err := parseBreakpoint(item)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("invalid breakpoint %s, because of %v", item, err))
+			continue
+		}
+		if seen[bp.key] {
+			invalid = append(invalid, fmt.Sprintf("duplicated breakpoint %s", item))
+			continue
+		}
+		seen[bp.key] = true
+		breakpoints = append(breakpoints, bp)
+	}
+	if len(invalid) != 0 {
+		return breakpoints, errors.New(strings.Join(invalid, "; "))
+	}
+	return breakpoints, nil
+}
+
+func parseBreakpoint(item string) (breakpoint, error) {
+	index := strings.LastIndex(item, "=")
+	if index < 0 {
+		return breakpoint{}, fmt.Errorf("expected [days ]time=targetSize")
+	}
+	size, err := strconv.Atoi(strings.TrimSpace(item[index+1:]))
+	if err != nil || size < 0 {
+		return breakpoint{}, fmt.Errorf("invalid targetSize %s", item[index+1:])
+	}
+	fields := strings.Fields(item[:index])
+	days := "*"
+	switch len(fields) {
+	case 1:
+	case 2:
+		days = fields[0]
+	default:
+		return breakpoint{}, fmt.Errorf("expected [days ]time=targetSize")
+	}
+	clock := fields[len(fields)-1]
+	t, err := time.Parse("15:04:05", clock)
+	if err != nil {
+		if t, err = time.Parse("15:04", clock); err != nil {
+			return breakpoint{}, fmt.Errorf("invalid time %s, expected HH:MM or HH:MM:SS", clock)
+		}
+	}
+	return breakpoint{
+		key:        fmt.Sprintf("%s=%d", strings.Join(fields, " "), size),
+		schedule:   fmt.Sprintf("%d %d %d * * %s", t.Second(), t.Minute(), t.Hour(), days),
+		targetSize: int32(size),
+	}, nil
+}
+
+// profilePosition returns the last breakpoint before now and the first breakpoint after now.
+func profilePosition(breakpoints []breakpoint, location *time.Location, now time.Time) (current, next string, nextTime time.Time) {
+	if location == nil {
+		location = time.Local
+	}
+	now = now.In(location)
+	var currentTime time.Time
+	for _, bp := range breakpoints {
+		schedule, err := ParseSchedule(bp.schedule)
+		if err != nil {
+			continue
+		}
+		// the last execution before now is found by the executions since a week ago.
+		for t := schedule.Next(now.AddDate(0, 0, -profileLookBackDays)); !t.IsZero() && !t.After(now); t = schedule.Next(t) {
+			if currentTime.IsZero() || t.After(currentTime) {
+				current, currentTime = bp.key, t
+			}
+		}
+		if t := schedule.Next(now); !t.IsZero() && (nextTime.IsZero() || t.Before(nextTime)) {
+			next, nextTime = bp.key, t
+		}
+	}
+	return current, next, nextTime
+}
+
+// profileJobs returns the jobs of the profile of the cronHPA in the engine by their breakpoints.
+func (cm *CronManager) profileJobs(owner types.NamespacedName) map[string]*CronJobHPA {
+	jobs := make(map[string]*CronJobHPA)
+	for _, job := range cm.registry.ofOwner(owner) {
+		if job.profile != "" {
+			jobs[job.profile] = job
+		}
+	}
+	return jobs
+}
+
+// syncProfile expands the profile of the cronHPA into a job for every breakpoint and returns the status of the profile.
+// The jobs of the breakpoints which are not changed keep running, the others are removed. A breakpoint which is
+// invalid or fails to be submitted fails the profile, but the jobs of the valid breakpoints keep running.
+// The id of a job is derived from the global params, so all the jobs are recreated when they are changed.
+func (cm *CronManager) syncProfile(instance *v1beta1.CronHorizontalPodAutoscaler, defaults *v1beta1.NamespaceDefaults) *v1beta1.ProfileStatus {
+	existing := cm.profileJobs(types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name})
+	defer func() {
+		for key, job := range existing {
+			if err := cm.delete(job.ID()); err != nil {
+				log.Errorf("Failed to delete profile job %s in cronHPA %s namespace %s,because of %v", key, instance.Name, instance.Namespace, err)
+			}
+		}
+	}()
+	if len(instance.Spec.Profile) == 0 {
+		return nil
+	}
+
+	status := &v1beta1.ProfileStatus{}
+	if instance.Status.ProfileStatus != nil {
+		status = instance.Status.ProfileStatus.DeepCopy()
+	}
+	// the probe time is kept if the failure is not changed, so the status isn't updated on every reconcile.
+	failed := func(message string) *v1beta1.ProfileStatus {
+		log.Errorf("Failed to sync profile of cronHPA %s in namespace %s,because of %s", instance.Name, instance.Namespace, message)
+		if status.State != v1beta1.Failed || status.Message != message || status.LastProbeTime == nil {
+			status.LastProbeTime = &metav1.Time{Time: time.Now()}
+		}
+		status.State = v1beta1.Failed
+		status.Message = message
+		return status
+	}
+
+	location, err := loadTimezone(instance.Spec.Timezone, defaults)
+	if err != nil {
+		// no job can be created in the timezone, the jobs keep running until it is fixed.
+		existing = nil
+		return failed(fmt.Sprintf("invalid timezone, because of %v", err))
+	}
+
+	failures := make([]string, 0)
+	breakpoints, err := parseProfile(instance.Spec.Profile)
+	if err != nil {
+		failures = append(failures, err.Error())
+	}
+
+	submitted := false
+	for _, bp := range breakpoints {
+		job := v1beta1.Job{
+			Name:           profileJobPrefix + bp.key,
+			Schedule:       bp.schedule,
+			ScheduleFormat: v1beta1.Seconds,
+			TargetSize:     bp.targetSize,
+		}
+		j, err := CronHPAJobFactory(instance, defaults, job, cm.scaler, cm.mapper, cm.dynamicClient, cm.jobClient)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("Failed to create cron hpa job of breakpoint %s,because of %v", bp.key, err))
+			// the job of the breakpoint keeps running with the last valid spec.
+			delete(existing, bp.key)
+			continue
+		}
+		ch := j.(*CronJobHPA)
+		ch.profile = bp.key
+		cm.applyJitter(ch)
+
+		// the key of a breakpoint contains its targetSize, so the job with the same id is updated in place.
+		if old, ok := existing[ch.profile]; ok && old.ID() == ch.ID() {
+			delete(existing, ch.profile)
+		}
+		if err := cm.createOrUpdate(ch); err != nil {
+			if _, ok := err.(*NoNeedUpdate); ok {
+				continue
+			}
+			failures = append(failures, fmt.Sprintf("Failed to update cron hpa job of breakpoint %s,because of %v", ch.profile, err))
+			delete(existing, ch.profile)
+			continue
+		}
+		submitted = true
+	}
+
+	status.Current, status.Next, status.NextTime = profileStatusPosition(breakpoints, location, time.Now())
+	if len(failures) != 0 {
+		return failed(strings.Join(failures, "; "))
+	}
+	if submitted || status.State == v1beta1.Failed {
+		status.State = v1beta1.Submitted
+		status.Message = fmt.Sprintf("cron hpa profile with %d breakpoints submitted.", len(breakpoints))
+		status.LastProbeTime = &metav1.Time{Time: time.Now()}
+	}
+	return status
+}
+
+func profileStatusPosition(breakpoints []breakpoint, location *time.Location, now time.Time) (string, string, *metav1.Time) {
+	current, next, nextTime := profilePosition(breakpoints, location, now)
+	if nextTime.IsZero() {
+		return current, next, nil
+	}
+	return current, next, &metav1.Time{Time: nextTime}
+}
+
+// setProfileResult records the result of a breakpoint in the profile status instead of a job condition.
+func setProfileResult(instance *v1beta1.CronHorizontalPodAutoscaler, job *CronJobHPA, condition v1beta1.Condition) {
+	status := &v1beta1.ProfileStatus{}
+	if instance.Status.ProfileStatus != nil {
+		status = instance.Status.ProfileStatus.DeepCopy()
+	}
+	status.State = condition.State
+	status.Message = condition.Message
+	status.LastProbeTime = &condition.LastProbeTime
+	status.Targets = condition.Targets
+	// the position is the one of the valid breakpoints.
+	breakpoints, _ := parseProfile(instance.Spec.Profile)
+	status.Current, status.Next, status.NextTime = profileStatusPosition(breakpoints, job.location, time.Now())
+	instance.Status.ProfileStatus = status
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// profileExecutor accepts every job without scheduling it.
+type profileExecutor struct {
+	CronExecutor
+}
+
+func (e *profileExecutor) AddJob(job CronJob) error    { return nil }
+func (e *profileExecutor) Update(job CronJob) error    { return nil }
+func (e *profileExecutor) RemoveJob(job CronJob) error { return nil }
+
+func newProfileCronHPA(profile ...string) *v1beta1.CronHorizontalPodAutoscaler {
+	return &v1beta1.CronHorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "profile"},
+		Spec: v1beta1.CronHorizontalPodAutoscalerSpec{
+			ScaleTargetRef: v1beta1.ScaleTargetRef{ApiVersion: "apps/v1", Kind: "Deployment", Name: "nginx"},
+			Profile:        profile,
+		},
+	}
+}
+
+func profileJobKeys(cm *CronManager) []string {
+	keys := make([]string, 0)
+	for key := range cm.profileJobs(types.NamespacedName{Namespace: "default", Name: "profile"}) {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestParseProfileKeepsValidBreakpoints(t *testing.T) {
+	breakpoints, err := parseProfile([]string{"08:00=10", "25:00=1", "MON-FRI 18:00:30=2", "08:00=10"})
+	if err == nil {
+		t.Errorf("parseProfile() doesn't return the error of the invalid breakpoints")
+	}
+	keys := make([]string, 0)
+	for _, bp := range breakpoints {
+		keys = append(keys, bp.key)
+	}
+	if want := []string{"08:00=10", "MON-FRI 18:00:30=2"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("parseProfile() = %v, want %v", keys, want)
+	}
+}
+
+func TestSyncProfile(t *testing.T) {
+	cm := &CronManager{registry: newJobRegistry(), cronExecutor: &profileExecutor{}}
+
+	instance := newProfileCronHPA("08:00=10", "18:00=2")
+	status := cm.syncProfile(instance, nil)
+	if status.State != v1beta1.Submitted {
+		t.Fatalf("syncProfile() state = %s, message %s", status.State, status.Message)
+	}
+	if keys, want := profileJobKeys(cm), []string{"08:00=10", "18:00=2"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("profile jobs = %v, want %v", keys, want)
+	}
+
+	// an invalid breakpoint fails the profile, but the jobs of the valid breakpoints keep running.
+	instance = newProfileCronHPA("08:00=10", "18:00=abc")
+	instance.Status.ProfileStatus = status
+	status = cm.syncProfile(instance, nil)
+	if status.State != v1beta1.Failed {
+		t.Fatalf("syncProfile() state = %s, want %s", status.State, v1beta1.Failed)
+	}
+	if keys, want := profileJobKeys(cm), []string{"08:00=10"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("profile jobs = %v, want %v", keys, want)
+	}
+	if status.Current == "" && status.Next == "" {
+		t.Errorf("syncProfile() has no position of the valid breakpoints")
+	}
+
+	// the same failure keeps the probe time, so the status is not updated again.
+	probeTime := status.LastProbeTime.DeepCopy()
+	instance.Status.ProfileStatus = status
+	status = cm.syncProfile(instance, nil)
+	if !status.LastProbeTime.Equal(probeTime) {
+		t.Errorf("syncProfile() changes the probe time of the same failure from %v to %v", probeTime, status.LastProbeTime)
+	}
+
+	// no job is created in an invalid timezone, the running jobs are kept.
+	instance = newProfileCronHPA("08:00=10", "20:00=1")
+	instance.Spec.Timezone = "Mars/Olympus"
+	instance.Status.ProfileStatus = status
+	status = cm.syncProfile(instance, nil)
+	if status.State != v1beta1.Failed {
+		t.Fatalf("syncProfile() state = %s, want %s", status.State, v1beta1.Failed)
+	}
+	if keys, want := profileJobKeys(cm), []string{"08:00=10"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("profile jobs = %v, want %v", keys, want)
+	}
+
+	// a fixed profile is submitted again.
+	instance = newProfileCronHPA("08:00=10", "20:00=1")
+	instance.Status.ProfileStatus = status
+	status = cm.syncProfile(instance, nil)
+	if status.State != v1beta1.Submitted {
+		t.Fatalf("syncProfile() state = %s, message %s", status.State, status.Message)
+	}
+	if keys, want := profileJobKeys(cm), []string{"08:00=10", "20:00=1"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("profile jobs = %v, want %v", keys, want)
+	}
+}
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"context"
+	autoscalingapi "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	scaleclient "k8s.io/client-go/scale"
+	"k8s.io/client-go/util/flowcontrol"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// newScaleLimiter returns the token bucket which limits all the calls of the jobs to the API server,
+// including the scale subresource, the updates of the HPAs and the reads of the dynamic client.
+// The calls are not limited if qps is 0.
+func newScaleLimiter(qps float32, burst int) flowcontrol.RateLimiter {
+	if qps <= 0 {
+		return nil
+	}
+	return flowcontrol.NewTokenBucketRateLimiter(qps, burst)
+}
+
+// rateLimitedScales limits the calls of the scale subresource with a token bucket.
+type rateLimitedScales struct {
+	scaleclient.ScalesGetter
+	limiter flowcontrol.RateLimiter
+}
+
+// newRateLimitedScales returns the scaler limited by the limiter, the scaler is not limited if the limiter is nil.
+func newRateLimitedScales(scaler scaleclient.ScalesGetter, limiter flowcontrol.RateLimiter) scaleclient.ScalesGetter {
+	if limiter == nil {
+		return scaler
+	}
+	return &rateLimitedScales{ScalesGetter: scaler, limiter: limiter}
+}
+
+func (rs *rateLimitedScales) Scales(namespace string) scaleclient.ScaleInterface {
+	return &rateLimitedScaleInterface{ScaleInterface: rs.ScalesGetter.Scales(namespace), limiter: rs.limiter}
+}
+
+type rateLimitedScaleInterface struct {
+	scaleclient.ScaleInterface
+	limiter flowcontrol.RateLimiter
+}
+
+func (rsi *rateLimitedScaleInterface) Get(ctx context.Context, resource schema.GroupResource, name string, opts metav1.GetOptions) (*autoscalingapi.Scale, error) {
+	if err := rsi.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return rsi.ScaleInterface.Get(ctx, resource, name, opts)
+}
+
+func (rsi *rateLimitedScaleInterface) Update(ctx context.Context, resource schema.GroupResource, scale *autoscalingapi.Scale, opts metav1.UpdateOptions) (*autoscalingapi.Scale, error) {
+	if err := rsi.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return rsi.ScaleInterface.Update(ctx, resource, scale, opts)
+}
+
+func (rsi *rateLimitedScaleInterface) Patch(ctx context.Context, gvr schema.GroupVersionResource, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions) (*autoscalingapi.Scale, error) {
+	if err := rsi.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return rsi.ScaleInterface.Patch(ctx, gvr, name, pt, data, opts)
+}
+
+// rateLimitedClient limits the writes of the client, the reads are served by the informer cache.
+type rateLimitedClient struct {
+	client.Client
+	limiter flowcontrol.RateLimiter
+}
+
+// newRateLimitedClient returns the client whose writes are limited by the limiter, the client is not limited if the limiter is nil.
+func newRateLimitedClient(c client.Client, limiter flowcontrol.RateLimiter) client.Client {
+	if limiter == nil {
+		return c
+	}
+	return &rateLimitedClient{Client: c, limiter: limiter}
+}
+
+func (rc *rateLimitedClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if err := rc.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return rc.Client.Update(ctx, obj, opts...)
+}
+
+func (rc *rateLimitedClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if err := rc.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return rc.Client.Patch(ctx, obj, patch, opts...)
+}
+
+// rateLimitedDynamic limits the calls of the namespaced resources of the dynamic client, which the jobs use
+// to list the targets of a selector, to read the readiness and to scale the targets by their replicasPath.
+type rateLimitedDynamic struct {
+	dynamic.Interface
+	limiter flowcontrol.RateLimiter
+}
+
+// newRateLimitedDynamic returns the dynamic client limited by the limiter, the client is not limited if the limiter is nil.
+func newRateLimitedDynamic(d dynamic.Interface, limiter flowcontrol.RateLimiter) dynamic.Interface {
+	if limiter == nil {
+		return d
+	}
+	return &rateLimitedDynamic{Interface: d, limiter: limiter}
+}
+
+func (rd *rateLimitedDynamic) Resource(resource schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &rateLimitedResource{NamespaceableResourceInterface: rd.Interface.Resource(resource), limiter: rd.limiter}
+}
+
+type rateLimitedResource struct {
+	dynamic.NamespaceableResourceInterface
+	limiter flowcontrol.RateLimiter
+}
+
+func (rr *rateLimitedResource) Namespace(namespace string) dynamic.ResourceInterface {
+	return &rateLimitedResourceInterface{ResourceInterface: rr.NamespaceableResourceInterface.Namespace(namespace), limiter: rr.limiter}
+}
+
+type rateLimitedResourceInterface struct {
+	dynamic.ResourceInterface
+	limiter flowcontrol.RateLimiter
+}
+
+func (rri *rateLimitedResourceInterface) Get(ctx context.Context, name string, opts metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if err := rri.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return rri.ResourceInterface.Get(ctx, name, opts, subresources...)
+}
+
+func (rri *rateLimitedResourceInterface) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	if err := rri.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return rri.ResourceInterface.List(ctx, opts)
+}
+
+func (rri *rateLimitedResourceInterface) Update(ctx context.Context, obj *unstructured.Unstructured, opts metav1.UpdateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if err := rri.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return rri.ResourceInterface.Update(ctx, obj, opts, subresources...)
+}
+
+func (rri *rateLimitedResourceInterface) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if err := rri.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return rri.ResourceInterface.Patch(ctx, name, pt, data, opts, subresources...)
+}
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/types"
+	"sync"
+)
+
+// jobRegistry holds the jobs in the engine by id, indexed by their owners and their targets,
+// so the jobs of an object are found and removed without scanning all the jobs.
+type jobRegistry struct {
+	lock     sync.RWMutex
+	jobs     map[string]*CronJobHPA
+	byOwner  map[string]map[string]*CronJobHPA
+	byTarget map[string]map[string]*CronJobHPA
+}
+
+func newJobRegistry() *jobRegistry {
+	return &jobRegistry{
+		jobs:     make(map[string]*CronJobHPA),
+		byOwner:  make(map[string]map[string]*CronJobHPA),
+		byTarget: make(map[string]map[string]*CronJobHPA),
+	}
+}
+
+// ownerOf returns the key of the cronHPA of the job, or the name of its cluster policy without a namespace.
+func ownerOf(job *CronJobHPA) types.NamespacedName {
+	if job.clusterPolicy != "" {
+		return types.NamespacedName{Name: job.clusterPolicy}
+	}
+	return types.NamespacedName{Namespace: job.HPARef.Namespace, Name: job.HPARef.Name}
+}
+
+// targetKey is the key of a workload in the target index.
+func targetKey(namespace, kind, name string) string {
+	return fmt.Sprintf("%s/%s/%s", namespace, kind, name)
+}
+
+// targetsOf returns the keys of the static targets of the job, the targets of a selector change on every execution.
+func targetsOf(job *CronJobHPA) []string {
+	keys := make([]string, 0, len(job.TargetRefs))
+	for _, ref := range job.TargetRefs {
+		keys = append(keys, targetKey(ref.RefNamespace, ref.RefKind, ref.RefName))
+	}
+	return keys
+}
+
+// add adds the job or replaces the job with the same id.
+func (r *jobRegistry) add(job *CronJobHPA) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.removeLocked(job.ID())
+	r.jobs[job.ID()] = job
+	addToIndex(r.byOwner, ownerOf(job).String(), job)
+	for _, key := range targetsOf(job) {
+		addToIndex(r.byTarget, key, job)
+	}
+}
+
+// remove removes the job and returns it, or nil if it is not found.
+func (r *jobRegistry) remove(id string) *CronJobHPA {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.removeLocked(id)
+}
+
+func (r *jobRegistry) removeLocked(id string) *CronJobHPA {
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil
+	}
+	delete(r.jobs, id)
+	removeFromIndex(r.byOwner, ownerOf(job).String(), id)
+	for _, key := range targetsOf(job) {
+		removeFromIndex(r.byTarget, key, id)
+	}
+	return job
+}
+
+func (r *jobRegistry) get(id string) (*CronJobHPA, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	job, ok := r.jobs[id]
+	return job, ok
+}
+
+func (r *jobRegistry) len() int {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return len(r.jobs)
+}
+
+// ofOwner returns the jobs of the cronHPA or the cluster policy.
+func (r *jobRegistry) ofOwner(owner types.NamespacedName) []*CronJobHPA {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return values(r.byOwner[owner.String()])
+}
+
+// ofTarget returns the jobs which scale the workload statically.
+func (r *jobRegistry) ofTarget(key string) []*CronJobHPA {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return values(r.byTarget[key])
+}
+
+// owners returns a snapshot of the jobs grouped by their owners, every group has at least one job.
+func (r *jobRegistry) owners() [][]*CronJobHPA {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	owners := make([][]*CronJobHPA, 0, len(r.byOwner))
+	for _, jobs := range r.byOwner {
+		owners = append(owners, values(jobs))
+	}
+	return owners
+}
+
+func addToIndex(index map[string]map[string]*CronJobHPA, key string, job *CronJobHPA) {
+	jobs, ok := index[key]
+	if !ok {
+		jobs = make(map[string]*CronJobHPA)
+		index[key] = jobs
+	}
+	jobs[job.ID()] = job
+}
+
+func removeFromIndex(index map[string]map[string]*CronJobHPA, key string, id string) {
+	jobs, ok := index[key]
+	if !ok {
+		return
+	}
+	delete(jobs, id)
+	if len(jobs) == 0 {
+		delete(index, key)
+	}
+}
+
+func values(jobs map[string]*CronJobHPA) []*CronJobHPA {
+	list := make([]*CronJobHPA, 0, len(jobs))
+	for _, job := range jobs {
+		list = append(list, job)
+	}
+	return list
+}
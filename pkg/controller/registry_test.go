@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	"github.com/ringtail/go-cron"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"testing"
+	"time"
+)
+
+const (
+	benchmarkOwners       = 10000
+	benchmarkJobsPerOwner = 2
+)
+
+// newBenchmarkJobs returns the jobs of the owners, every job scales its own deployment.
+func newBenchmarkJobs(owners, jobsPerOwner int) []*CronJobHPA {
+	jobs := make([]*CronJobHPA, 0, owners*jobsPerOwner)
+	for i := 0; i < owners; i++ {
+		hpa := &v1beta1.CronHorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: fmt.Sprintf("cronhpa-%d", i)},
+		}
+		for j := 0; j < jobsPerOwner; j++ {
+			jobs = append(jobs, &CronJobHPA{
+				name:   fmt.Sprintf("job-%d", j),
+				id:     fmt.Sprintf("%d-%d", i, j),
+				HPARef: hpa,
+				TargetRefs: []*TargetRef{
+					{RefName: fmt.Sprintf("nginx-%d", i), RefNamespace: "default", RefKind: "Deployment", RefGroup: "apps", RefVersion: "v1"},
+				},
+			})
+		}
+	}
+	return jobs
+}
+
+func newBenchmarkRegistry(jobs []*CronJobHPA) *jobRegistry {
+	registry := newJobRegistry()
+	for _, job := range jobs {
+		registry.add(job)
+	}
+	return registry
+}
+
+func TestJobRegistry(t *testing.T) {
+	jobs := newBenchmarkJobs(3, 2)
+	registry := newBenchmarkRegistry(jobs)
+	if registry.len() != 6 {
+		t.Fatalf("len() = %d, want 6", registry.len())
+	}
+
+	owner := types.NamespacedName{Namespace: "default", Name: "cronhpa-1"}
+	if n := len(registry.ofOwner(owner)); n != 2 {
+		t.Errorf("ofOwner(%s) returns %d jobs, want 2", owner, n)
+	}
+	if n := len(registry.ofTarget(targetKey("default", "Deployment", "nginx-1"))); n != 2 {
+		t.Errorf("ofTarget() returns %d jobs, want 2", n)
+	}
+	if n := len(registry.owners()); n != 3 {
+		t.Errorf("owners() returns %d groups, want 3", n)
+	}
+
+	// a job with the same id replaces the old one and its index entries.
+	registry.add(&CronJobHPA{
+		name:       jobs[2].name,
+		id:         jobs[2].id,
+		HPARef:     jobs[2].HPARef,
+		TargetRefs: []*TargetRef{{RefName: "nginx-x", RefNamespace: "default", RefKind: "Deployment"}},
+	})
+	if registry.len() != 6 {
+		t.Errorf("len() = %d after the replacement, want 6", registry.len())
+	}
+	if n := len(registry.ofTarget(targetKey("default", "Deployment", "nginx-1"))); n != 1 {
+		t.Errorf("ofTarget() returns %d jobs after the replacement, want 1", n)
+	}
+
+	for _, job := range registry.ofOwner(owner) {
+		if registry.remove(job.ID()) == nil {
+			t.Errorf("remove(%s) doesn't find the job", job.ID())
+		}
+	}
+	if n := len(registry.ofOwner(owner)); n != 0 {
+		t.Errorf("ofOwner(%s) returns %d jobs after the removal, want 0", owner, n)
+	}
+	if _, ok := registry.byTarget[targetKey("default", "Deployment", "nginx-x")]; ok {
+		t.Errorf("the empty target index entry is not removed")
+	}
+	if registry.remove("unknown") != nil {
+		t.Errorf("remove() of an unknown id returns a job")
+	}
+}
+
+func BenchmarkJobRegistryAdd(b *testing.B) {
+	jobs := newBenchmarkJobs(benchmarkOwners, benchmarkJobsPerOwner)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		newBenchmarkRegistry(jobs)
+	}
+}
+
+func BenchmarkJobRegistryOfOwner(b *testing.B) {
+	registry := newBenchmarkRegistry(newBenchmarkJobs(benchmarkOwners, benchmarkJobsPerOwner))
+	owner := types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("cronhpa-%d", benchmarkOwners/2)}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if len(registry.ofOwner(owner)) != benchmarkJobsPerOwner {
+			b.Fatalf("jobs of %s are not found", owner)
+		}
+	}
+}
+
+func BenchmarkJobRegistryRemoveOwner(b *testing.B) {
+	jobs := newBenchmarkJobs(benchmarkOwners, benchmarkJobsPerOwner)
+	registry := newBenchmarkRegistry(jobs)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		n := i % benchmarkOwners
+		owner := types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("cronhpa-%d", n)}
+		for _, job := range registry.ofOwner(owner) {
+			registry.remove(job.ID())
+		}
+		// the jobs are added back out of the timer, so every iteration removes one owner of the full registry.
+		b.StopTimer()
+		for _, job := range jobs[n*benchmarkJobsPerOwner : (n+1)*benchmarkJobsPerOwner] {
+			registry.add(job)
+		}
+		b.StartTimer()
+	}
+}
+
+// gcClient returns an empty owner for every get, as the informer cache does for a cronHPA without conditions.
+type gcClient struct {
+	client.Client
+}
+
+func (c *gcClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	return nil
+}
+
+// gcExecutor lists an entry for every job which runs in an hour.
+type gcExecutor struct {
+	CronExecutor
+	entries []*cron.Entry
+}
+
+func (e *gcExecutor) ListEntries() []*cron.Entry {
+	return e.entries
+}
+
+func BenchmarkGC(b *testing.B) {
+	jobs := newBenchmarkJobs(benchmarkOwners, benchmarkJobsPerOwner)
+	executor := &gcExecutor{entries: make([]*cron.Entry, 0, len(jobs))}
+	for _, job := range jobs {
+		executor.entries = append(executor.entries, &cron.Entry{Next: time.Now().Add(time.Hour), Job: job})
+	}
+	cm := &CronManager{
+		client:        &gcClient{},
+		registry:      newBenchmarkRegistry(jobs),
+		cronExecutor:  executor,
+		eventRecorder: record.NewFakeRecorder(0),
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cm.GC()
+	}
+	if cm.registry.len() != len(jobs) {
+		b.Fatalf("GC removes %d jobs which are found in the cron engine", len(jobs)-cm.registry.len())
+	}
+}
@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	log "k8s.io/klog/v2"
+	"strconv"
+	"strings"
+)
+
+// parseReplicasPath converts the replicasPath of a target into a JSON pointer. It is a JSON pointer such as
+// /spec/replicas, or a JSONPath of fields and array indexes such as .spec.replicas, {.spec.workers[0].replicas}
+// or $['spec']['replicas']. An empty path means the scale subresource is used.
+func parseReplicasPath(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", nil
+	}
+	if strings.HasPrefix(path, "/") {
+		if _, err := pointerSegments(path); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+
+	expr := path
+	if strings.HasPrefix(expr, "{") && strings.HasSuffix(expr, "}") {
+		expr = expr[1 : len(expr)-1]
+	}
+	expr = strings.TrimPrefix(expr, "$")
+	segments := make([]string, 0)
+	for i := 0; i < len(expr); {
+		switch expr[i] {
+		case '.':
+			j := i + 1
+			for j < len(expr) && expr[j] != '.' && expr[j] != '[' {
+				j++
+			}
+			if j == i+1 {
+				return "", fmt.Errorf("invalid replicasPath %s, because of an empty field at %d", path, i)
+			}
+			segments = append(segments, expr[i+1:j])
+			i = j
+		case '[':
+			j := strings.IndexByte(expr[i:], ']')
+			if j < 0 {
+				return "", fmt.Errorf("invalid replicasPath %s, because of an unclosed bracket at %d", path, i)
+			}
+			key := expr[i+1 : i+j]
+			if unquoted := strings.Trim(key, "'\""); len(unquoted) == len(key)-2 && len(key) >= 2 {
+				segments = append(segments, unquoted)
+			} else if _, err := strconv.Atoi(key); err == nil {
+				segments = append(segments, key)
+			} else {
+				return "", fmt.Errorf("invalid replicasPath %s, because only the fields and the array indexes are supported", path)
+			}
+			i += j + 1
+		default:
+			// the leading dot of the first field is optional.
+			if i != 0 {
+				return "", fmt.Errorf("invalid replicasPath %s, because of an unexpected %q at %d", path, expr[i], i)
+			}
+			expr = "." + expr
+		}
+	}
+	if len(segments) == 0 {
+		return "", fmt.Errorf("invalid replicasPath %s, because it has no field", path)
+	}
+
+	escaper := strings.NewReplacer("~", "~0", "/", "~1")
+	for i := range segments {
+		segments[i] = escaper.Replace(segments[i])
+	}
+	return "/" + strings.Join(segments, "/"), nil
+}
+
+// pointerSegments returns the unescaped segments of the JSON pointer.
+func pointerSegments(pointer string) ([]string, error) {
+	if pointer == "/" || !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid replicasPath %s, because it is not a JSON pointer of a field", pointer)
+	}
+	unescaper := strings.NewReplacer("~1", "/", "~0", "~")
+	segments := strings.Split(pointer[1:], "/")
+	for i := range segments {
+		segments[i] = unescaper.Replace(segments[i])
+	}
+	return segments, nil
+}
+
+// replicasAt returns the integer at the JSON pointer in the object.
+func replicasAt(obj map[string]interface{}, pointer string) (int64, error) {
+	segments, err := pointerSegments(pointer)
+	if err != nil {
+		return 0, err
+	}
+	var value interface{} = obj
+	for _, s := range segments {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			field, ok := v[s]
+			if !ok {
+				return 0, fmt.Errorf("field %s of replicasPath %s is not found", s, pointer)
+			}
+			value = field
+		case []interface{}:
+			index, err := strconv.Atoi(s)
+			if err != nil || index < 0 || index >= len(v) {
+				return 0, fmt.Errorf("index %s of replicasPath %s is out of range", s, pointer)
+			}
+			value = v[index]
+		default:
+			return 0, fmt.Errorf("replicasPath %s doesn't point to a field at %s", pointer, s)
+		}
+	}
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case float64:
+		if v == float64(int64(v)) {
+			return int64(v), nil
+		}
+	}
+	return 0, fmt.Errorf("replicasPath %s points to %v which is not an integer", pointer, value)
+}
+
+// replicasChanged tells whether a JSON patch failed at its test. The API server rejects the patch which
+// could not be applied as invalid without the causes, which the validation of the object has.
+func replicasChanged(err error) bool {
+	status, ok := err.(apierrors.APIStatus)
+	if !ok || !apierrors.IsInvalid(err) {
+		return false
+	}
+	details := status.Status().Details
+	return details == nil || len(details.Causes) == 0
+}
+
+// pathResource returns the dynamic client of the target and the resource of its mapping.
+func (ch *CronJobHPA) pathResource(ref *TargetRef) (dynamic.ResourceInterface, schema.GroupVersionResource, error) {
+	mapping, err := ch.mapper.RESTMapping(schema.GroupKind{Group: ref.RefGroup, Kind: ref.RefKind}, ref.RefVersion)
+	if err != nil {
+		return nil, schema.GroupVersionResource{}, fmt.Errorf("Failed to create mapping,because of %v", err)
+	}
+	return ch.dynamicClient.Resource(mapping.Resource).Namespace(ref.RefNamespace), mapping.Resource, nil
+}
+
+// pathReplicas reads the replicas of the target at its replicasPath.
+func (ch *CronJobHPA) pathReplicas(ref *TargetRef) (*unstructured.Unstructured, int64, error) {
+	resource, gvr, err := ch.pathResource(ref)
+	if err != nil {
+		return nil, 0, err
+	}
+	ch.countAPICall("get", gvr.Resource)
+	obj, err := resource.Get(context.Background(), ref.RefName, metav1.GetOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	current, err := replicasAt(obj.Object, ref.ReplicasPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	return obj, current, nil
+}
+
+// ScalePathRef scales a target without the scale subresource by a JSON patch of its replicasPath.
+// The patch tests the replicas which are read, so it fails and is retried if they are changed in between.
+func (ch *CronJobHPA) ScalePathRef(ref *TargetRef, desiredSize int32) (msg string, err error) {
+	obj, current, err := ch.pathReplicas(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to get replicas of %s %s in %s namespace at %s, because of %v", ref.RefKind, ref.RefName, ref.RefNamespace, ref.ReplicasPath, err)
+	}
+
+	patch, err := json.Marshal([]map[string]interface{}{
+		{"op": "test", "path": ref.ReplicasPath, "value": current},
+		{"op": "replace", "path": ref.ReplicasPath, "value": desiredSize},
+	})
+	if err != nil {
+		return "", err
+	}
+	resource, gvr, err := ch.pathResource(ref)
+	if err != nil {
+		return "", err
+	}
+	ch.countAPICall("patch", gvr.Resource)
+	if _, err = resource.Patch(context.Background(), obj.GetName(), types.JSONPatchType, patch, metav1.PatchOptions{}); err != nil {
+		if replicasChanged(err) {
+			// the test of the patch failed, which is the conflict of the scale subresource.
+			err = apierrors.NewConflict(gvr.GroupResource(), obj.GetName(), fmt.Errorf("replicas at %s are changed from %d", ref.ReplicasPath, current))
+		}
+		log.Errorf("failed to scale %s %s in %s namespace to %d, because of %v", ref.RefKind, ref.RefName, ref.RefNamespace, desiredSize, err)
+		return "", fmt.Errorf("failed to scale %s %s in %s namespace to %d, because of %w", ref.RefKind, ref.RefName, ref.RefNamespace, desiredSize, err)
+	}
+	log.Infof("%s %s in namespace %s has been scaled successfully at %s. job: %s replicas: %d id: %s", ref.RefKind, ref.RefName, ref.RefNamespace, ref.ReplicasPath, ch.Name(), desiredSize, ch.ID())
+	return fmt.Sprintf("current replicas:%d, desired replicas:%d.", current, desiredSize), nil
+}
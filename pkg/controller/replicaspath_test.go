@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	autoscalingapi "k8s.io/api/autoscaling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/dynamic"
+	fakescale "k8s.io/client-go/scale/fake"
+	core "k8s.io/client-go/testing"
+	"strings"
+	"testing"
+)
+
+// pathDynamic returns the target with 2 replicas and rejects the patches with patchErr.
+type pathDynamic struct {
+	dynamic.Interface
+	patchErr error
+	patches  []string
+}
+
+func (d *pathDynamic) Resource(resource schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &pathResource{dynamic: d}
+}
+
+type pathResource struct {
+	dynamic.NamespaceableResourceInterface
+	dynamic *pathDynamic
+}
+
+func (r *pathResource) Namespace(namespace string) dynamic.ResourceInterface { return r }
+
+func (r *pathResource) Get(ctx context.Context, name string, opts metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": name, "namespace": "default"},
+		"spec":     map[string]interface{}{"replicas": int64(2)},
+	}}, nil
+}
+
+func (r *pathResource) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	r.dynamic.patches = append(r.dynamic.patches, string(data))
+	if r.dynamic.patchErr != nil {
+		return nil, r.dynamic.patchErr
+	}
+	return &unstructured.Unstructured{}, nil
+}
+
+func TestScalePathRef(t *testing.T) {
+	d := &pathDynamic{}
+	job := &CronJobHPA{name: "scale-up", dynamicClient: d, mapper: newTestMapper()}
+	ref := &TargetRef{RefName: "nginx", RefNamespace: "default", RefKind: "Deployment", RefGroup: "apps", RefVersion: "v1", ReplicasPath: "/spec/replicas"}
+	msg, err := job.ScalePathRef(ref, 3)
+	if err != nil {
+		t.Fatalf("ScalePathRef() error = %v", err)
+	}
+	if msg != "current replicas:2, desired replicas:3." {
+		t.Errorf("ScalePathRef() = %q", msg)
+	}
+	if want := `[{"op":"test","path":"/spec/replicas","value":2},{"op":"replace","path":"/spec/replicas","value":3}]`; len(d.patches) != 1 || d.patches[0] != want {
+		t.Errorf("patches = %v, want %s", d.patches, want)
+	}
+}
+
+func TestScalePathRefConflict(t *testing.T) {
+	ref := &TargetRef{RefName: "nginx", RefNamespace: "default", RefKind: "Deployment", RefGroup: "apps", RefVersion: "v1"}
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+
+	// the scale subresource is changed in between.
+	scaler := &fakescale.FakeScaleClient{}
+	scaler.AddReactor("patch", "*", func(action core.Action) (bool, runtime.Object, error) {
+		return true, &autoscalingapi.Scale{}, apierrors.NewConflict(deployments, "nginx", fmt.Errorf("the object has been modified"))
+	})
+	scaleJob := &CronJobHPA{name: "scale-up", scaler: scaler, mapper: newTestMapper()}
+	_, scaleErr := scaleJob.ScalePlainRef(ref, 3)
+
+	// the test of the replicas fails, which the API server rejects like a patch which could not be applied.
+	pathRef := *ref
+	pathRef.ReplicasPath = "/spec/replicas"
+	testFailed := apierrors.NewGenericServerResponse(422, "", schema.GroupResource{}, "", "testing value /spec/replicas failed: test failed", 0, false)
+	pathJob := &CronJobHPA{name: "scale-up", dynamicClient: &pathDynamic{patchErr: testFailed}, mapper: newTestMapper()}
+	_, pathErr := pathJob.ScalePathRef(&pathRef, 3)
+
+	for name, err := range map[string]error{"scale subresource": scaleErr, "replicasPath": pathErr} {
+		if !apierrors.IsConflict(err) {
+			t.Errorf("error of %s = %v, want a conflict", name, err)
+		}
+		if prefix := `failed to scale Deployment nginx in default namespace to 3, because of Operation cannot be fulfilled on deployments.apps "nginx"`; err == nil || !strings.HasPrefix(err.Error(), prefix) {
+			t.Errorf("error of %s = %v, want prefix %s", name, err, prefix)
+		}
+	}
+
+	// an invalid value isn't a conflict.
+	invalid := apierrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "Deployment"}, "nginx",
+		field.ErrorList{field.Invalid(field.NewPath("spec", "replicas"), 3, "must be less than or equal to 2")})
+	pathJob = &CronJobHPA{name: "scale-up", dynamicClient: &pathDynamic{patchErr: invalid}, mapper: newTestMapper()}
+	if _, err := pathJob.ScalePathRef(&pathRef, 3); apierrors.IsConflict(err) || !apierrors.IsInvalid(err) {
+		t.Errorf("error of an invalid value = %v, want invalid", err)
+	}
+}
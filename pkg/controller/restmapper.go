@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"context"
+	scalelib "github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/lib"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/cache"
+	log "k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sync"
+	"time"
+)
+
+// mapperResetInterval limits the resets of the discovery cache by the kinds which are not found,
+// so a target with a wrong kind doesn't fetch the discovery information on every execution.
+const mapperResetInterval = 30 * time.Second
+
+// refreshingRESTMapper maps the kinds with the discovery information cached in memory, which is fetched
+// on the first lookup instead of the start of the controller. The cache is reset when a kind is not found
+// or a CRD is changed, so the targets whose CRDs are installed after the start are found.
+type refreshingRESTMapper struct {
+	*restmapper.DeferredDiscoveryRESTMapper
+	scaleKindResolver scalelib.ResettableScaleKindResolver
+
+	lock      sync.Mutex
+	lastReset time.Time
+}
+
+// newRefreshingRESTMapper returns the mapper and the scale kind resolver which share the discovery cache.
+func newRefreshingRESTMapper(client discovery.DiscoveryInterface) *refreshingRESTMapper {
+	cached := memory.NewMemCacheClient(client)
+	return &refreshingRESTMapper{
+		DeferredDiscoveryRESTMapper: restmapper.NewDeferredDiscoveryRESTMapper(cached),
+		scaleKindResolver:           scalelib.NewDiscoveryScaleKindResolver(cached),
+	}
+}
+
+func (m *refreshingRESTMapper) RESTMapping(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+	mapping, err := m.DeferredDiscoveryRESTMapper.RESTMapping(gk, versions...)
+	if meta.IsNoMatchError(err) && m.resetOnNoMatch(gk) {
+		mapping, err = m.DeferredDiscoveryRESTMapper.RESTMapping(gk, versions...)
+	}
+	return mapping, err
+}
+
+func (m *refreshingRESTMapper) RESTMappings(gk schema.GroupKind, versions ...string) ([]*meta.RESTMapping, error) {
+	mappings, err := m.DeferredDiscoveryRESTMapper.RESTMappings(gk, versions...)
+	if meta.IsNoMatchError(err) && m.resetOnNoMatch(gk) {
+		mappings, err = m.DeferredDiscoveryRESTMapper.RESTMappings(gk, versions...)
+	}
+	return mappings, err
+}
+
+// resetOnNoMatch resets the cache if it is not reset in the interval, and returns whether it is reset.
+func (m *refreshingRESTMapper) resetOnNoMatch(gk schema.GroupKind) bool {
+	m.lock.Lock()
+	if time.Since(m.lastReset) < mapperResetInterval {
+		m.lock.Unlock()
+		return false
+	}
+	m.lastReset = time.Now()
+	m.lock.Unlock()
+
+	log.Infof("Reset the discovery cache, because no mapping is found for %s", gk)
+	m.reset()
+	return true
+}
+
+// reset drops the discovery information and the scale kinds, which are fetched again on the next lookup.
+func (m *refreshingRESTMapper) reset() {
+	m.DeferredDiscoveryRESTMapper.Reset()
+	m.scaleKindResolver.Reset()
+}
+
+// watchCRDs resets the mapper when a CRD is created, deleted or changes its versions.
+func (m *refreshingRESTMapper) watchCRDs(mgr manager.Manager) error {
+	informer, err := mgr.GetCache().GetInformer(context.TODO(), &apiextensionsv1.CustomResourceDefinition{})
+	if err != nil {
+		return err
+	}
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			m.reset()
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldCRD, ok := oldObj.(*apiextensionsv1.CustomResourceDefinition)
+			if !ok {
+				return
+			}
+			newCRD, ok := newObj.(*apiextensionsv1.CustomResourceDefinition)
+			if !ok {
+				return
+			}
+			if !apiequality.Semantic.DeepEqual(oldCRD.Spec.Versions, newCRD.Spec.Versions) ||
+				!apiequality.Semantic.DeepEqual(oldCRD.Status.AcceptedNames, newCRD.Status.AcceptedNames) {
+				log.Infof("Reset the discovery cache, because CRD %s is changed", newCRD.Name)
+				m.reset()
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			m.reset()
+		},
+	})
+	return nil
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	log "k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// NewScalingCalendarReconciler returns the reconciler which reports the iCalendar sources of the ScalingCalendars.
+func NewScalingCalendarReconciler(mgr manager.Manager) *ReconcileScalingCalendar {
+	setICalendarReader(mgr.GetAPIReader())
+	return &ReconcileScalingCalendar{Client: mgr.GetClient()}
+}
+
+var _ reconcile.Reconciler = &ReconcileScalingCalendar{}
+
+// ReconcileScalingCalendar reconciles a ScalingCalendar object
+type ReconcileScalingCalendar struct {
+	client.Client
+}
+
+// SetupWithManager watches the calendars and the metadata of the ConfigMaps of their iCalendar sources.
+func (r *ReconcileScalingCalendar) SetupWithManager(mgr manager.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1beta1.ScalingCalendar{}).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.mapToCalendars), builder.OnlyMetadata).
+		Complete(r)
+}
+
+func (r *ReconcileScalingCalendar) mapToCalendars(obj client.Object) []reconcile.Request {
+	list := &v1beta1.ScalingCalendarList{}
+	if err := r.List(context.Background(), list); err != nil {
+		log.Errorf("Failed to list scalingCalendars,because of %v", err)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0)
+	for _, item := range list.Items {
+		for _, ref := range item.Spec.ICalendars {
+			if ref.Namespace == obj.GetNamespace() && ref.Name == obj.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: item.Name}})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// +kubebuilder:rbac:groups=autoscaling.alibabacloud.com,resources=scalingcalendars,verbs=get;list;watch;update
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+func (r *ReconcileScalingCalendar) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	instance := &v1beta1.ScalingCalendar{}
+	if err := r.Get(ctx, request.NamespacedName, instance); err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	var conditions []v1beta1.CalendarCondition
+	for _, ref := range instance.Spec.ICalendars {
+		if ref.Namespace == "" {
+			conditions = append(conditions, v1beta1.CalendarCondition{
+				Source:  icalSource(ref.Namespace, ref),
+				Message: "namespace of the configmap is required in a scalingCalendar",
+			})
+			continue
+		}
+		// the dates are parsed again in the timezone of the jobs, the condition only reports the source.
+		_, condition := loadICalendar(ctx, r.Client, ref.Namespace, ref, nil)
+		conditions = append(conditions, condition)
+	}
+
+	if apiequality.Semantic.DeepEqual(instance.Status.Conditions, conditions) {
+		return reconcile.Result{}, nil
+	}
+	instance.Status.Conditions = conditions
+	if err := r.Update(ctx, instance); err != nil {
+		log.Errorf("Failed to update scalingCalendar %s status, because of %v", instance.Name, err)
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
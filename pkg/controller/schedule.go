@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	"github.com/ringtail/go-cron"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// starBit marks a field with "*" in cron.SpecSchedule.
+	starBit = 1 << 63
+	// includeLookAheadDays limits the search of the next included day.
+	includeLookAheadDays = 366
+)
+
+// normalizeSchedule converts the schedule in the format to the 6-field format beginning with seconds.
+// The descriptors beginning with "@", such as "@every 1h" and "@date ...", are not changed.
+func normalizeSchedule(schedule string, format v1beta1.ScheduleFormat) (string, error) {
+	schedule = strings.TrimSpace(schedule)
+	if strings.HasPrefix(schedule, "@") {
+		return schedule, nil
+	}
+	fields := strings.Fields(schedule)
+	switch format {
+	case "", v1beta1.Seconds:
+		return schedule, nil
+	case v1beta1.Standard:
+		if len(fields) != 5 {
+			return "", fmt.Errorf("schedule %s has %d fields, but the Standard scheduleFormat expects 5 fields (minute hour day-of-month month day-of-week)", schedule, len(fields))
+		}
+	case v1beta1.Auto:
+		switch len(fields) {
+		case 5:
+		case 6, 7:
+			return schedule, nil
+		default:
+			return "", fmt.Errorf("schedule %s has %d fields, but the Auto scheduleFormat expects 5 fields (minute first), 6 fields (second first) or 7 fields (second first with year)", schedule, len(fields))
+		}
+	default:
+		return "", fmt.Errorf("unknown scheduleFormat %s", format)
+	}
+	fields[4] = normalizeStandardDow(fields[4])
+	// the job fires at the first second of the minute.
+	return "0 " + strings.Join(fields, " "), nil
+}
+
+// normalizeStandardDow maps the day of week 7, which is Sunday in the standard crontab, to 0 in the items
+// like "7", "7#2" and "7L". The ranges ending at 7 like "1-7" and "5-7/2" are expanded into the days.
+// The other items are not changed and the invalid ones are reported by the parser.
+func normalizeStandardDow(field string) string {
+	items := strings.Split(field, ",")
+	for i, item := range items {
+		switch {
+		case item == "7":
+			items[i] = "0"
+		case strings.HasPrefix(item, "7#"):
+			items[i] = "0" + strings.TrimPrefix(item, "7")
+		case item == "7L" || item == "7l":
+			items[i] = "0L"
+		default:
+			rangeAndStep := strings.SplitN(item, "/", 2)
+			bounds := strings.SplitN(rangeAndStep[0], "-", 2)
+			if len(bounds) != 2 || bounds[1] != "7" {
+				continue
+			}
+			from, ok := dayNames[strings.ToUpper(bounds[0])]
+			if !ok {
+				n, err := strconv.Atoi(bounds[0])
+				if err != nil || n < 0 || n > 7 {
+					continue
+				}
+				from = time.Weekday(n)
+			}
+			step := 1
+			if len(rangeAndStep) == 2 {
+				n, err := strconv.Atoi(rangeAndStep[1])
+				if err != nil || n < 1 {
+					continue
+				}
+				step = n
+			}
+			days := make([]string, 0)
+			for day := int(from); day <= 7; day += step {
+				days = append(days, strconv.Itoa(day%7))
+			}
+			items[i] = strings.Join(days, ",")
+		}
+	}
+	return strings.Join(items, ",")
+}
+
+// Schedule parses the plan of the job and applies the includeDates, the timezone, the dstPolicy,
+// the jitter and the lead time of the job.
+func (ch *CronJobHPA) Schedule() (cron.Schedule, error) {
+	schedule, err := ch.wallClockSchedule()
+	if err != nil || ch.offset() == 0 {
+		return schedule, err
+	}
+	return newOffsetSchedule(schedule, ch.offset()), nil
+}
+
+func (ch *CronJobHPA) wallClockSchedule() (cron.Schedule, error) {
+	schedule, err := ParseSchedule(ch.Plan)
+	if err != nil {
+		return nil, err
+	}
+	// the included dates are resolved once, Next is called on every entry whenever the engine sorts them.
+	if dates := ch.includedDates(); len(dates) != 0 {
+		switch s := schedule.(type) {
+		case *cron.SpecSchedule:
+			schedule = newIncludeSchedule(s, newTimeOfDay(s), dates)
+		case *extendedSchedule:
+			schedule = newIncludeSchedule(s, s.timeOfDay, dates)
+		}
+	}
+	// the fixed intervals of @every don't depend on the timezone, the parser returns them by value.
+	switch schedule.(type) {
+	case cron.ConstantDelaySchedule, *cron.ConstantDelaySchedule:
+		return schedule, nil
+	}
+	return newDSTSchedule(schedule, ch.location, ch.dstPolicy, ch.setDSTAdjustment), nil
+}
+
+// includeSchedule also fires on the included days at the time of day of the schedule,
+// even if the day of month, month or day of week of the schedule doesn't match.
+type includeSchedule struct {
+	cron.Schedule
+	timeOfDay *cron.SpecSchedule
+	dates     []string
+}
+
+func newIncludeSchedule(schedule cron.Schedule, timeOfDay *cron.SpecSchedule, dates []string) *includeSchedule {
+	return &includeSchedule{
+		Schedule:  schedule,
+		timeOfDay: timeOfDay,
+		dates:     dates,
+	}
+}
+
+// newTimeOfDay returns the schedule which fires every day at the time of day of the spec.
+func newTimeOfDay(spec *cron.SpecSchedule) *cron.SpecSchedule {
+	return &cron.SpecSchedule{
+		Second: spec.Second,
+		Minute: spec.Minute,
+		Hour:   spec.Hour,
+		Dom:    bitsOf(1, 31) | starBit,
+		Month:  bitsOf(1, 12) | starBit,
+		Dow:    bitsOf(0, 6) | starBit,
+	}
+}
+
+func (is *includeSchedule) Next(t time.Time) time.Time {
+	next := is.Schedule.Next(t)
+	dates := is.dates
+	if len(dates) == 0 {
+		return next
+	}
+
+	deadline := t.AddDate(0, 0, includeLookAheadDays)
+	candidate := t
+	for {
+		candidate = is.timeOfDay.Next(candidate)
+		if candidate.IsZero() || candidate.After(deadline) || (!next.IsZero() && !candidate.Before(next)) {
+			return next
+		}
+		if included, _ := IsTodayIncluded(dates, candidate); included {
+			return candidate
+		}
+		// try the next day.
+		candidate = time.Date(candidate.Year(), candidate.Month(), candidate.Day()+1, 0, 0, 0, 0, candidate.Location()).Add(-time.Second)
+	}
+}
+
+func bitsOf(min, max uint) uint64 {
+	var bits uint64
+	for i := min; i <= max; i++ {
+		bits |= 1 << i
+	}
+	return bits
+}
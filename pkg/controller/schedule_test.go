@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	"testing"
+	"time"
+)
+
+func TestNormalizeScheduleDayOfWeek(t *testing.T) {
+	testCases := []struct {
+		schedule string
+		format   v1beta1.ScheduleFormat
+		want     string
+	}{
+		{schedule: "0 8 * * 7", format: v1beta1.Standard, want: "0 0 8 * * 0"},
+		{schedule: "0 8 * * 1-7", format: v1beta1.Standard, want: "0 0 8 * * 1,2,3,4,5,6,0"},
+		{schedule: "0 8 * * 5-7", format: v1beta1.Auto, want: "0 0 8 * * 5,6,0"},
+		{schedule: "0 8 * * 1-7/2", format: v1beta1.Standard, want: "0 0 8 * * 1,3,5,0"},
+		{schedule: "0 8 * * FRI-7", format: v1beta1.Standard, want: "0 0 8 * * 5,6,0"},
+		{schedule: "0 8 * * 1,3,7", format: v1beta1.Standard, want: "0 0 8 * * 1,3,0"},
+		{schedule: "0 8 * * 7#2", format: v1beta1.Standard, want: "0 0 8 * * 0#2"},
+		{schedule: "0 8 * * 7L", format: v1beta1.Standard, want: "0 0 8 * * 0L"},
+		{schedule: "0 8 * * 1-5", format: v1beta1.Standard, want: "0 0 8 * * 1-5"},
+		{schedule: "0 8 * * *", format: v1beta1.Standard, want: "0 0 8 * * *"},
+		{schedule: "0 17 * * MON-FRI", format: v1beta1.Standard, want: "0 0 17 * * MON-FRI"},
+		// the 6-field format is not changed.
+		{schedule: "0 0 8 * * 7", format: v1beta1.Seconds, want: "0 0 8 * * 7"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.schedule, func(t *testing.T) {
+			plan, err := normalizeSchedule(tc.schedule, tc.format)
+			if err != nil {
+				t.Fatalf("normalizeSchedule(%q) error = %v", tc.schedule, err)
+			}
+			if plan != tc.want {
+				t.Errorf("normalizeSchedule(%q) = %q, want %q", tc.schedule, plan, tc.want)
+			}
+		})
+	}
+}
+
+func TestStandardSundayFires(t *testing.T) {
+	// 2026-10-17 is a Saturday.
+	from := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		schedule string
+		next     time.Time
+	}{
+		{schedule: "0 8 * * 7", next: time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)},
+		{schedule: "0 8 * * 1-7", next: time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)},
+		{schedule: "0 8 * * 1,7", next: time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)},
+		{schedule: "0 8 * * 7#3", next: time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)},
+		{schedule: "0 8 * * 1-5", next: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.schedule, func(t *testing.T) {
+			plan, err := normalizeSchedule(tc.schedule, v1beta1.Standard)
+			if err != nil {
+				t.Fatalf("normalizeSchedule(%q) error = %v", tc.schedule, err)
+			}
+			schedule, err := ParseSchedule(plan)
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q) error = %v", plan, err)
+			}
+			if next := schedule.Next(from); !next.Equal(tc.next) {
+				t.Errorf("Next() of %q = %v, want %v", tc.schedule, next, tc.next)
+			}
+		})
+	}
+}
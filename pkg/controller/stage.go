@@ -0,0 +1,310 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	autoscalingapi "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	log "k8s.io/klog/v2"
+	"sync"
+	"time"
+)
+
+const (
+	defaultReadyTimeout = 5 * time.Minute
+	readyPollInterval   = 5 * time.Second
+	// unstagedStageName is shown for the implicit stage of the targets which are not in any stage.
+	unstagedStageName = "(unstaged targets)"
+)
+
+type executionStage struct {
+	name         string
+	targets      []int
+	waitForReady bool
+	readyTimeout time.Duration
+}
+
+// planStages groups the targets into stages in the order of execution.
+// Without stages all the targets are scaled together in one stage.
+func planStages(refs []*TargetRef, stages []v1beta1.ScalingStage, scaleDownOrder []string, scaleUp bool) []executionStage {
+	if len(stages) == 0 {
+		all := make([]int, len(refs))
+		for i := range refs {
+			all[i] = i
+		}
+		return []executionStage{{targets: all}}
+	}
+
+	staged := make(map[int]bool)
+	planned := make([]executionStage, 0, len(stages)+1)
+	for _, stage := range stages {
+		es := executionStage{
+			name:         stage.Name,
+			targets:      make([]int, 0),
+			waitForReady: stage.WaitForReady,
+			readyTimeout: defaultReadyTimeout,
+		}
+		if stage.ReadyTimeoutSeconds > 0 {
+			es.readyTimeout = time.Duration(stage.ReadyTimeoutSeconds) * time.Second
+		}
+		for i, ref := range refs {
+			for _, name := range stage.Targets {
+				if name == ref.RefName && !staged[i] {
+					staged[i] = true
+					es.targets = append(es.targets, i)
+				}
+			}
+		}
+		planned = append(planned, es)
+	}
+
+	rest := executionStage{targets: make([]int, 0)}
+	for i := range refs {
+		if !staged[i] {
+			rest.targets = append(rest.targets, i)
+		}
+	}
+	if len(rest.targets) != 0 {
+		planned = append(planned, rest)
+	}
+
+	if scaleUp {
+		return planned
+	}
+
+	// scale-down runs in reverse unless the order is given.
+	ordered := make([]executionStage, 0, len(planned))
+	if len(scaleDownOrder) != 0 {
+		used := make(map[int]bool)
+		for _, name := range scaleDownOrder {
+			for i, es := range planned {
+				if es.name == name && es.name != "" && !used[i] {
+					used[i] = true
+					ordered = append(ordered, es)
+				}
+			}
+		}
+		// stages which are not in scaleDownOrder keep the reverse order.
+		for i := len(planned) - 1; i >= 0; i-- {
+			if !used[i] {
+				ordered = append(ordered, planned[i])
+			}
+		}
+		return ordered
+	}
+	for i := len(planned) - 1; i >= 0; i-- {
+		ordered = append(ordered, planned[i])
+	}
+	return ordered
+}
+
+// runStages scales the targets stage by stage. A failed stage stops the later ones.
+func (ch *CronJobHPA) runStages(refs []*TargetRef, sizes []int32, pause func(d time.Duration)) []TargetResult {
+	stages := planStages(refs, ch.stages, ch.scaleDownOrder, len(ch.stages) == 0 || ch.isScaleUp(refs, sizes))
+	return runPlannedStages(stages, refs, sizes,
+		func(ref *TargetRef, desiredSize int32) (string, error) {
+			return ch.scaleTarget(ref, desiredSize, pause)
+		},
+		func(ref *TargetRef, desiredSize int32, timeout time.Duration) error {
+			return ch.waitForReady(ref, desiredSize, timeout, pause)
+		})
+}
+
+// runPlannedStages scales the targets of the stages in order with scale, and waits for them with wait
+// if the stage asks for it. The targets of the stages after a failed one are skipped.
+func runPlannedStages(stages []executionStage, refs []*TargetRef, sizes []int32,
+	scale func(ref *TargetRef, desiredSize int32) (string, error),
+	wait func(ref *TargetRef, desiredSize int32, timeout time.Duration) error) []TargetResult {
+	results := make([]TargetResult, len(refs))
+
+	// the implicit stage has no name, so the failure is tracked apart from the name of the stage.
+	failed := false
+	failedStage := ""
+	for _, stage := range stages {
+		if failed {
+			for _, i := range stage.targets {
+				results[i] = TargetResult{Ref: refs[i], DesiredSize: sizes[i], Stage: stage.name, Skipped: true,
+					Msg: fmt.Sprintf("skip scaling activity,because stage %s failed.", failedStage)}
+			}
+			continue
+		}
+
+		var wg sync.WaitGroup
+		for _, i := range stage.targets {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				msg, err := scale(refs[i], sizes[i])
+				results[i] = TargetResult{Ref: refs[i], DesiredSize: sizes[i], Stage: stage.name, Msg: msg, Err: err}
+			}(i)
+		}
+		wg.Wait()
+
+		for _, i := range stage.targets {
+			if results[i].Err != nil {
+				failed = true
+			}
+		}
+		if !failed && stage.waitForReady {
+			for _, i := range stage.targets {
+				if err := wait(refs[i], sizes[i], stage.readyTimeout); err != nil {
+					results[i].Err = err
+					failed = true
+				}
+			}
+		}
+		if failed {
+			failedStage = stage.displayName()
+		}
+	}
+	return results
+}
+
+// displayName names the implicit stage of the targets which are not in any stage.
+func (es executionStage) displayName() string {
+	if es.name == "" {
+		return unstagedStageName
+	}
+	return es.name
+}
+
+// isScaleUp tells whether the execution scales the targets up in total.
+func (ch *CronJobHPA) isScaleUp(refs []*TargetRef, sizes []int32) bool {
+	current, desired, err := ch.totalReplicas(refs, sizes)
+	if err != nil {
+		log.Warningf("Failed to get current replicas and use the scale-up order,because of %v", err)
+		return true
+	}
+	return desired >= current
+}
+
+// totalReplicas returns the sum of the current replicas of the targets and the sum of the sizes.
+func (ch *CronJobHPA) totalReplicas(refs []*TargetRef, sizes []int32) (current int32, desired int32, err error) {
+	for i, ref := range refs {
+		replicas, err := ch.currentReplicas(ref)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get current replicas of %s %s in %s namespace, because of %v", ref.RefKind, ref.RefName, ref.RefNamespace, err)
+		}
+		current += replicas
+		desired += sizes[i]
+	}
+	return current, desired, nil
+}
+
+func (ch *CronJobHPA) currentReplicas(ref *TargetRef) (int32, error) {
+	if ref.RefKind == "HorizontalPodAutoscaler" {
+		hpa := &autoscalingapi.HorizontalPodAutoscaler{}
+		if err := ch.client.Get(context.Background(), types.NamespacedName{Namespace: ref.RefNamespace, Name: ref.RefName}, hpa); err != nil {
+			return 0, err
+		}
+		return hpa.Status.CurrentReplicas, nil
+	}
+	if ref.ReplicasPath != "" {
+		_, replicas, err := ch.pathReplicas(ref)
+		return int32(replicas), err
+	}
+	scale, err := ch.getScale(ref)
+	if err != nil {
+		return 0, err
+	}
+	return scale.Spec.Replicas, nil
+}
+
+func (ch *CronJobHPA) getScale(ref *TargetRef) (*autoscalingapi.Scale, error) {
+	mappings, err := ch.mapper.RESTMappings(schema.GroupKind{Group: ref.RefGroup, Kind: ref.RefKind})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create mapping,because of %v", err)
+	}
+	for _, mapping := range mappings {
+		ch.countAPICall("get", "scale")
+		scale, err := ch.scaler.Scales(ref.RefNamespace).Get(context.Background(), mapping.Resource.GroupResource(), ref.RefName, metav1.GetOptions{})
+		if err == nil {
+			return scale, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to find source target %s %s in %s namespace", ref.RefKind, ref.RefName, ref.RefNamespace)
+}
+
+// waitForReady polls the target until the desired replicas are ready or the timeout is reached, and pauses between the polls.
+func (ch *CronJobHPA) waitForReady(ref *TargetRef, desiredSize int32, timeout time.Duration, pause func(d time.Duration)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ready, err := ch.isReady(ref, desiredSize, false)
+		if err == nil && ready {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return fmt.Errorf("%s %s in %s namespace is not ready after %v, because of %v", ref.RefKind, ref.RefName, ref.RefNamespace, timeout, err)
+			}
+			return fmt.Errorf("%s %s in %s namespace is not ready after %v", ref.RefKind, ref.RefName, ref.RefNamespace, timeout)
+		}
+		pause(readyPollInterval)
+	}
+}
+
+// isReady checks the readyReplicas of workloads which report it and the scale status of the others.
+// For a HorizontalPodAutoscaler the workload it scales is checked, and it may run more replicas than desired.
+func (ch *CronJobHPA) isReady(ref *TargetRef, desiredSize int32, atLeast bool) (bool, error) {
+	if ref.RefKind == "HorizontalPodAutoscaler" {
+		hpa := &autoscalingapi.HorizontalPodAutoscaler{}
+		if err := ch.client.Get(context.Background(), types.NamespacedName{Namespace: ref.RefNamespace, Name: ref.RefName}, hpa); err != nil {
+			return false, err
+		}
+		gv, err := schema.ParseGroupVersion(hpa.Spec.ScaleTargetRef.APIVersion)
+		if err != nil {
+			return false, err
+		}
+		return ch.isReady(&TargetRef{
+			RefName:      hpa.Spec.ScaleTargetRef.Name,
+			RefNamespace: ref.RefNamespace,
+			RefKind:      hpa.Spec.ScaleTargetRef.Kind,
+			RefGroup:     gv.Group,
+			RefVersion:   gv.Version,
+		}, desiredSize, true)
+	}
+
+	mapping, err := ch.mapper.RESTMapping(schema.GroupKind{Group: ref.RefGroup, Kind: ref.RefKind}, ref.RefVersion)
+	if err != nil {
+		return false, fmt.Errorf("Failed to create mapping,because of %v", err)
+	}
+	ch.countAPICall("get", mapping.Resource.Resource)
+	obj, err := ch.dynamicClient.Resource(mapping.Resource).Namespace(ref.RefNamespace).Get(context.Background(), ref.RefName, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	replicas, replicasFound, _ := unstructured.NestedInt64(obj.Object, "status", "replicas")
+	readyReplicas, readyFound, _ := unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+	if readyFound || replicasFound {
+		if atLeast {
+			return readyReplicas >= int64(desiredSize), nil
+		}
+		// scale-up waits for the ready pods and scale-down waits for the pods to be removed.
+		return replicas == int64(desiredSize) && readyReplicas >= int64(desiredSize), nil
+	}
+
+	// a workload without the scale subresource and the status is ready when the replicas at its replicasPath are.
+	if ref.ReplicasPath != "" {
+		replicas, err := replicasAt(obj.Object, ref.ReplicasPath)
+		if err != nil {
+			return false, err
+		}
+		if atLeast {
+			return replicas >= int64(desiredSize), nil
+		}
+		return replicas == int64(desiredSize), nil
+	}
+
+	scale, err := ch.getScale(ref)
+	if err != nil {
+		return false, err
+	}
+	if atLeast {
+		return scale.Status.Replicas >= desiredSize, nil
+	}
+	return scale.Status.Replicas == desiredSize, nil
+}
@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newStageRefs(names ...string) []*TargetRef {
+	refs := make([]*TargetRef, 0, len(names))
+	for _, name := range names {
+		refs = append(refs, &TargetRef{RefName: name, RefNamespace: "default", RefKind: "Deployment", RefGroup: "apps", RefVersion: "v1"})
+	}
+	return refs
+}
+
+func stageNames(stages []executionStage) []string {
+	names := make([]string, 0, len(stages))
+	for _, stage := range stages {
+		names = append(names, stage.displayName())
+	}
+	return names
+}
+
+func TestPlanStages(t *testing.T) {
+	refs := newStageRefs("db", "api", "web", "worker")
+	stages := []v1beta1.ScalingStage{
+		{Name: "backend", Targets: []string{"db"}},
+		{Name: "frontend", Targets: []string{"api", "web"}},
+	}
+	testCases := []struct {
+		name           string
+		stages         []v1beta1.ScalingStage
+		scaleDownOrder []string
+		scaleUp        bool
+		want           []string
+	}{
+		{
+			name:    "no stages",
+			scaleUp: true,
+			want:    []string{unstagedStageName},
+		},
+		{
+			name:    "scale-up",
+			stages:  stages,
+			scaleUp: true,
+			want:    []string{"backend", "frontend", unstagedStageName},
+		},
+		{
+			name:   "scale-down runs the unstaged targets first",
+			stages: stages,
+			want:   []string{unstagedStageName, "frontend", "backend"},
+		},
+		{
+			name:           "scale-down order",
+			stages:         stages,
+			scaleDownOrder: []string{"backend"},
+			want:           []string{"backend", unstagedStageName, "frontend"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			planned := planStages(refs, tc.stages, tc.scaleDownOrder, tc.scaleUp)
+			if names := stageNames(planned); !reflect.DeepEqual(names, tc.want) {
+				t.Errorf("planStages() = %v, want %v", names, tc.want)
+			}
+		})
+	}
+}
+
+func TestRunPlannedStagesStopsAfterFailure(t *testing.T) {
+	refs := newStageRefs("db", "api", "worker")
+	sizes := []int32{1, 2, 3}
+	stages := []v1beta1.ScalingStage{
+		{Name: "backend", Targets: []string{"db"}},
+		{Name: "frontend", Targets: []string{"api"}, WaitForReady: true},
+	}
+	noWait := func(ref *TargetRef, desiredSize int32, timeout time.Duration) error { return nil }
+
+	testCases := []struct {
+		name    string
+		scaleUp bool
+		fail    string
+		waitErr string
+		scaled  []string
+		skipped map[string]string
+	}{
+		{
+			// on scale-down the implicit stage of the unstaged targets runs first.
+			name:    "unstaged stage fails on scale-down",
+			fail:    "worker",
+			scaled:  []string{"worker"},
+			skipped: map[string]string{"api": unstagedStageName, "db": unstagedStageName},
+		},
+		{
+			name:    "named stage fails on scale-up",
+			scaleUp: true,
+			fail:    "db",
+			scaled:  []string{"db"},
+			skipped: map[string]string{"api": "backend", "worker": "backend"},
+		},
+		{
+			name:    "wait fails on scale-down",
+			waitErr: "api",
+			scaled:  []string{"worker", "api"},
+			skipped: map[string]string{"db": "frontend"},
+		},
+		{
+			name:    "no failure",
+			scaleUp: true,
+			scaled:  []string{"db", "api", "worker"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var lock sync.Mutex
+			scaled := make([]string, 0)
+			scale := func(ref *TargetRef, desiredSize int32) (string, error) {
+				lock.Lock()
+				defer lock.Unlock()
+				scaled = append(scaled, ref.RefName)
+				if ref.RefName == tc.fail {
+					return "", fmt.Errorf("failed to scale %s", ref.RefName)
+				}
+				return "scaled", nil
+			}
+			wait := noWait
+			if tc.waitErr != "" {
+				wait = func(ref *TargetRef, desiredSize int32, timeout time.Duration) error {
+					if ref.RefName == tc.waitErr {
+						return fmt.Errorf("%s is not ready", ref.RefName)
+					}
+					return nil
+				}
+			}
+
+			planned := planStages(refs, stages, nil, tc.scaleUp)
+			results := runPlannedStages(planned, refs, sizes, scale, wait)
+			if !reflect.DeepEqual(scaled, tc.scaled) {
+				t.Errorf("scaled %v, want %v", scaled, tc.scaled)
+			}
+			for i, result := range results {
+				name := refs[i].RefName
+				failedStage, skip := tc.skipped[name]
+				if result.Skipped != skip {
+					t.Errorf("target %s skipped = %v, want %v", name, result.Skipped, skip)
+					continue
+				}
+				if skip {
+					if msg := fmt.Sprintf("skip scaling activity,because stage %s failed.", failedStage); result.Msg != msg {
+						t.Errorf("target %s msg = %q, want %q", name, result.Msg, msg)
+					}
+					continue
+				}
+				if (result.Err != nil) != (name == tc.fail || name == tc.waitErr) {
+					t.Errorf("target %s error = %v", name, result.Err)
+				}
+			}
+		})
+	}
+}
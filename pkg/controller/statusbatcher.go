@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	log "k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sync"
+	"time"
+)
+
+// statusBatcher coalesces the status updates of the same object within a window, so the jobs of
+// an object which fire at the same time write its status once instead of once for every job.
+type statusBatcher struct {
+	lock   sync.Mutex
+	client client.Client
+	// reader reads from the API server instead of the cache after a conflict.
+	reader   client.Reader
+	recorder record.EventRecorder
+	window   time.Duration
+	pending  map[string]*statusBatch
+}
+
+type statusBatch struct {
+	resource  string
+	key       types.NamespacedName
+	newObject func() client.Object
+	updates   []statusUpdate
+}
+
+type statusUpdate struct {
+	// apply changes the status of the object, it is applied again to the object fetched after a conflict.
+	apply func(obj client.Object)
+	// done is called with the object and the error of the write.
+	done func(obj client.Object, err error)
+}
+
+// newStatusBatcher returns the batcher, the updates are written at once if window is 0.
+func newStatusBatcher(c client.Client, reader client.Reader, recorder record.EventRecorder, window time.Duration) *statusBatcher {
+	return &statusBatcher{
+		client:   c,
+		reader:   reader,
+		recorder: recorder,
+		window:   window,
+		pending:  make(map[string]*statusBatch),
+	}
+}
+
+// add queues the update of the object of the resource, which is written with the other updates of the object in the window.
+func (b *statusBatcher) add(resource string, key types.NamespacedName, newObject func() client.Object, update statusUpdate) {
+	if b.window <= 0 {
+		b.write(&statusBatch{resource: resource, key: key, newObject: newObject, updates: []statusUpdate{update}})
+		return
+	}
+
+	id := resource + "/" + key.String()
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if batch, ok := b.pending[id]; ok {
+		batch.updates = append(batch.updates, update)
+		return
+	}
+	b.pending[id] = &statusBatch{resource: resource, key: key, newObject: newObject, updates: []statusUpdate{update}}
+	time.AfterFunc(b.window, func() {
+		b.lock.Lock()
+		batch := b.pending[id]
+		delete(b.pending, id)
+		b.lock.Unlock()
+		b.write(batch)
+	})
+}
+
+// write patches the status with all the updates of the batch. The object is read from the cache,
+// so the patch is guarded by the resourceVersion. After a conflict the cache may still hold the stale
+// object, so the object is read from the API server for the retries.
+func (b *statusBatcher) write(batch *statusBatch) {
+	var reader client.Reader = b.client
+	obj := batch.newObject()
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		// a new object is decoded every time, so nothing of the last attempt is left in it.
+		obj = batch.newObject()
+		if err := reader.Get(context.TODO(), batch.key, obj); err != nil {
+			return err
+		}
+		deepCopy := obj.DeepCopyObject().(client.Object)
+		for _, u := range batch.updates {
+			u.apply(obj)
+		}
+		countAPICall("patch", batch.resource)
+		err := b.client.Patch(context.TODO(), obj, client.MergeFromWithOptions(deepCopy, client.MergeFromWithOptimisticLock{}))
+		if errors.IsConflict(err) && b.reader != nil {
+			reader = b.reader
+		}
+		return err
+	})
+	if err != nil {
+		log.Errorf("Failed to update the status of %s %s with %d updates,because of %v", batch.resource, batch.key, len(batch.updates), err)
+		b.recordLost(batch, obj, err)
+	} else {
+		log.V(2).Infof("Update the status of %s %s with %d updates", batch.resource, batch.key, len(batch.updates))
+	}
+	KubeStatusUpdatesPerWrite.Observe(float64(len(batch.updates)))
+	for _, u := range batch.updates {
+		if u.done != nil {
+			u.done(obj, err)
+		}
+	}
+}
+
+// recordLost emits an event on the object whose updates are not written, unless the object is deleted.
+func (b *statusBatcher) recordLost(batch *statusBatch, obj client.Object, err error) {
+	if b.recorder == nil || errors.IsNotFound(err) {
+		return
+	}
+	// the object is not fetched if the read fails.
+	if obj.GetName() == "" {
+		obj.SetNamespace(batch.key.Namespace)
+		obj.SetName(batch.key.Name)
+	}
+	b.recorder.Event(obj, v1.EventTypeWarning, "StatusUpdateLost", fmt.Sprintf("Failed to update the status with %d updates, because of %v", len(batch.updates), err))
+}
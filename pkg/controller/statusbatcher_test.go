@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"context"
+	"github.com/AliyunContainerService/kubernetes-cronhpa-controller/pkg/apis/autoscaling/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+	"testing"
+)
+
+// versionedReader returns the cronHPA at the resourceVersion.
+type versionedReader struct {
+	resourceVersion string
+	reads           int
+}
+
+func (r *versionedReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	r.reads++
+	instance := obj.(*v1beta1.CronHorizontalPodAutoscaler)
+	instance.Namespace = key.Namespace
+	instance.Name = key.Name
+	instance.ResourceVersion = r.resourceVersion
+	return nil
+}
+
+func (r *versionedReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return nil
+}
+
+// conflictClient reads from a stale cache and accepts the patches of the latest resourceVersion only.
+type conflictClient struct {
+	client.Client
+	cache   *versionedReader
+	latest  string
+	patched []*v1beta1.CronHorizontalPodAutoscaler
+}
+
+func (c *conflictClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	return c.cache.Get(ctx, key, obj)
+}
+
+func (c *conflictClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if obj.GetResourceVersion() != c.latest {
+		return apierrors.NewConflict(schema.GroupResource{Group: "autoscaling.alibabacloud.com", Resource: "cronhorizontalpodautoscalers"}, obj.GetName(), nil)
+	}
+	c.patched = append(c.patched, obj.(*v1beta1.CronHorizontalPodAutoscaler).DeepCopy())
+	return nil
+}
+
+func newConditionUpdate(name string, errs *[]error) statusUpdate {
+	return statusUpdate{
+		apply: func(obj client.Object) {
+			instance := obj.(*v1beta1.CronHorizontalPodAutoscaler)
+			instance.Status.Conditions = setJobCondition(instance.Status.Conditions, v1beta1.Condition{Name: name, State: v1beta1.Succeed})
+		},
+		done: func(obj client.Object, err error) {
+			*errs = append(*errs, err)
+		},
+	}
+}
+
+func TestStatusBatcherRetriesFromAPIReader(t *testing.T) {
+	c := &conflictClient{cache: &versionedReader{resourceVersion: "1"}, latest: "2"}
+	apiReader := &versionedReader{resourceVersion: "2"}
+	recorder := record.NewFakeRecorder(10)
+	batcher := newStatusBatcher(c, apiReader, recorder, 0)
+
+	errs := make([]error, 0)
+	key := types.NamespacedName{Namespace: "default", Name: "cronhpa"}
+	batcher.add("cronhorizontalpodautoscalers", key, newCronHPAObject, newConditionUpdate("scale-up", &errs))
+
+	if len(errs) != 1 || errs[0] != nil {
+		t.Fatalf("the update isn't written, errors %v", errs)
+	}
+	if apiReader.reads != 1 {
+		t.Errorf("the object is read %d times from the API server after the conflict, want 1", apiReader.reads)
+	}
+	if len(c.patched) != 1 || len(c.patched[0].Status.Conditions) != 1 {
+		t.Fatalf("patched %v, want one condition", c.patched)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("unexpected event %s", <-recorder.Events)
+	}
+}
+
+func TestStatusBatcherRecordsLostUpdates(t *testing.T) {
+	// the object keeps changing, so every patch conflicts.
+	c := &conflictClient{cache: &versionedReader{resourceVersion: "1"}, latest: "3"}
+	recorder := record.NewFakeRecorder(10)
+	batcher := newStatusBatcher(c, &versionedReader{resourceVersion: "2"}, recorder, 0)
+
+	errs := make([]error, 0)
+	key := types.NamespacedName{Namespace: "default", Name: "cronhpa"}
+	batcher.write(&statusBatch{
+		resource:  "cronhorizontalpodautoscalers",
+		key:       key,
+		newObject: newCronHPAObject,
+		updates:   []statusUpdate{newConditionUpdate("scale-up", &errs), newConditionUpdate("scale-down", &errs)},
+	})
+
+	if len(errs) != 2 || !apierrors.IsConflict(errs[0]) {
+		t.Fatalf("errors %v, want two conflicts", errs)
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("%d events are emitted, want 1", len(recorder.Events))
+	}
+	if event := <-recorder.Events; !strings.HasPrefix(event, "Warning StatusUpdateLost Failed to update the status with 2 updates") {
+		t.Errorf("unexpected event %s", event)
+	}
+}
+
+func TestStatusBatcherIgnoresDeletedObject(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	batcher := newStatusBatcher(&conflictClient{}, nil, recorder, 0)
+	batcher.recordLost(&statusBatch{key: types.NamespacedName{Namespace: "default", Name: "cronhpa"}}, &v1beta1.CronHorizontalPodAutoscaler{},
+		apierrors.NewNotFound(schema.GroupResource{Resource: "cronhorizontalpodautoscalers"}, "cronhpa"))
+	if len(recorder.Events) != 0 {
+		t.Errorf("unexpected event %s of a deleted object", <-recorder.Events)
+	}
+}
@@ -71,6 +71,13 @@ func (r *discoveryScaleResolver) ScaleForResource(inputRes schema.GroupVersionRe
 	return schema.GroupVersionKind{}, fmt.Errorf("could not find scale subresource for %s in discovery information", inputRes.String())
 }
 
+// ResettableScaleKindResolver is a ScaleKindResolver whose cache can be dropped,
+// such as when the versions of a CRD are changed.
+type ResettableScaleKindResolver interface {
+	scale.ScaleKindResolver
+	Reset()
+}
+
 // cachedScaleKindResolver is a ScaleKindResolver that caches results
 // from another ScaleKindResolver, re-fetching on cache misses.
 type cachedScaleKindResolver struct {
@@ -104,9 +111,16 @@ func (r *cachedScaleKindResolver) ScaleForResource(resource schema.GroupVersionR
 	return gvk, nil
 }
 
+// Reset drops all the cached results, which are fetched again on the next calls.
+func (r *cachedScaleKindResolver) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache = make(map[schema.GroupVersionResource]schema.GroupVersionKind)
+}
+
 // NewDiscoveryScaleKindResolver creates a new ScaleKindResolver which uses information from the given
 // disovery client to resolve the correct Scale GroupVersionKind for different resources.
-func NewDiscoveryScaleKindResolver(client discovery.ServerResourcesInterface) scale.ScaleKindResolver {
+func NewDiscoveryScaleKindResolver(client discovery.ServerResourcesInterface) ResettableScaleKindResolver {
 	base := &discoveryScaleResolver{
 		discoveryClient: client,
 	}